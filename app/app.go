@@ -1,34 +1,88 @@
 package app
 
 import (
+	"claude-squad/config"
 	"claude-squad/keys"
 	"claude-squad/log"
+	"claude-squad/notify"
 	"claude-squad/session"
+	"claude-squad/session/git"
+	"claude-squad/session/tmux"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-const GlobalInstanceLimit = 10
-
 // Run is the main entrypoint into the application.
-func Run(ctx context.Context, program string, autoYes bool) error {
+func Run(ctx context.Context, appConfig *config.Config, program string, autoYes bool, defaultPath string) error {
 	p := tea.NewProgram(
-		newHome(ctx, program, autoYes),
+		newHome(ctx, appConfig, program, autoYes, defaultPath),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(), // Mouse scroll
+		// We handle signals ourselves so the instances are saved before quitting.
+		tea.WithoutSignalHandler(),
 	)
+	stop := forwardSignals(p)
+	defer stop()
 	_, err := p.Run()
 	return err
 }
 
+// shutdownTimeout is how long to wait for the app to save and quit after a signal before killing it.
+const shutdownTimeout = 5 * time.Second
+
+// shutdownMsg asks the app to save its state and quit, ex. because the process got a SIGTERM.
+type shutdownMsg struct{}
+
+// forwardSignals turns SIGINT and SIGTERM into a shutdownMsg, so the app saves its instances before quitting like
+// when the user quits. If the app doesn't quit within shutdownTimeout, ex. because it's attached to an instance,
+// or a second signal arrives, the program is killed, which still restores the terminal.
+func forwardSignals(p *tea.Program) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		var sig os.Signal
+		select {
+		case <-done:
+			return
+		case sig = <-sigs:
+		}
+		log.InfoLog.Printf("received %v, saving and quitting", sig)
+		// Send blocks until Update takes the message, so it can't hold up the timeout.
+		go p.Send(shutdownMsg{})
+		select {
+		case <-done:
+		case <-sigs:
+			log.WarningLog.Printf("received a second signal, quitting without saving")
+			p.Kill()
+		case <-time.After(shutdownTimeout):
+			log.WarningLog.Printf("timed out saving, quitting without saving")
+			p.Kill()
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
+
 type state int
 
 const (
@@ -37,19 +91,37 @@ const (
 	stateNew
 	// statePrompt is the state when the user is entering a prompt.
 	statePrompt
+	// stateTextInput is the state when the user is typing into a text input overlay other than the prompt.
+	stateTextInput
+	// stateConfirm is the state when the user is asked to confirm an action.
+	stateConfirm
+	// stateSelect is the state when the user is picking an item from a selection overlay.
+	stateSelect
+	// stateLog is the state when the user is viewing the log.
+	stateLog
+	// stateSummary is the state when the user is viewing the summary of all instances.
+	stateSummary
+	// stateStarting is the state while a new instance's worktree and tmux session are created in the background.
+	// Keys are ignored so the instance can't be changed while it starts.
+	stateStarting
 )
 
 type home struct {
 	ctx context.Context
 
+	appConfig *config.Config
+
 	program string
 	autoYes bool
+	// defaultPath is the repository path new instances are created in.
+	defaultPath string
 
 	// ui components
 	list         *ui.List
 	menu         *ui.Menu
 	tabbedWindow *ui.TabbedWindow
 	errBox       *ui.ErrBox
+	statusBar    *ui.StatusBar
 	// global spinner instance. we plumb this down to where it's needed
 	spinner spinner.Model
 
@@ -64,15 +136,66 @@ type home struct {
 
 	// promptAfterName tracks if we should enter prompt mode after naming
 	promptAfterName bool
+	// quitAfterStart is set if the user quit while an instance was starting. The app quits once it has started,
+	// so its worktree and tmux session are saved rather than left behind.
+	quitAfterStart bool
 
 	// textInputOverlay is the component for handling text input with state
 	textInputOverlay *overlay.TextInputOverlay
+	// textInputOnSubmit is called with the value of the text input overlay when it's submitted in
+	// stateTextInput.
+	textInputOnSubmit func(value string) (tea.Model, tea.Cmd)
+
+	// confirmationOverlay asks the user to confirm an action in stateConfirm
+	confirmationOverlay *overlay.ConfirmationOverlay
+	// confirmOnConfirm is called if the user confirms the action
+	confirmOnConfirm func() (tea.Model, tea.Cmd)
+
+	// selectionOverlay lets the user pick an item in stateSelect
+	selectionOverlay *overlay.SelectionOverlay
+	// selectOnSelect is called with the index of the item the user picked
+	selectOnSelect func(idx int) (tea.Model, tea.Cmd)
+	// selectOnCancel is called if the user closes the selection overlay without picking an item. It may be nil.
+	selectOnCancel func() (tea.Model, tea.Cmd)
+
+	// logOverlay shows the end of the log in stateLog. It's refreshed on every preview tick.
+	logOverlay *overlay.TextViewerOverlay
+	// summaryOverlay shows the totals of all instances in stateSummary. It's refreshed on every preview tick.
+	summaryOverlay *overlay.TextViewerOverlay
 
 	// keySent is used to manage underlines
 	keySent bool
+
+	// lowPower slows down the tick intervals to save CPU.
+	lowPower bool
+
+	// lastActivity is the last time each instance's output changed. Used to auto-pause idle instances.
+	lastActivity map[*session.Instance]time.Time
+	// lastChecked is the last time each instance's pane was checked. Idle instances are checked less often.
+	lastChecked map[*session.Instance]time.Time
+	// waitingForUser tracks the instances which are waiting for the user to answer a prompt, as of their last
+	// check.
+	waitingForUser map[*session.Instance]bool
+	// notified tracks the instances we've sent a notification for since they started waiting for input, so each
+	// prompt only notifies once.
+	notified map[*session.Instance]bool
+	// needsAttention tracks the instances which are waiting for input or whose diff couldn't be computed, as of
+	// the last metadata tick.
+	needsAttention map[*session.Instance]bool
+	// autoSaving is set while an auto-save is being written, so saves don't pile up if writing is slow.
+	autoSaving bool
+	// lastSound is the last time a sound was played for each instance.
+	lastSound map[*session.Instance]time.Time
+	// diffFailing tracks the instances whose diff couldn't be computed on the last check, so the sound is only
+	// played when it starts failing.
+	diffFailing map[*session.Instance]bool
+	// workspace is the name of the workspace shown, or empty if every instance is shown.
+	workspace string
+	// jumpCount is the number typed before a jump key, ex. 25 for 25g. 0 if none was typed.
+	jumpCount int
 }
 
-func newHome(ctx context.Context, program string, autoYes bool) *home {
+func newHome(ctx context.Context, appConfig *config.Config, program string, autoYes bool, defaultPath string) *home {
 	// Initialize storage
 	storage, err := session.NewStorage()
 	if err != nil {
@@ -80,21 +203,61 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		os.Exit(1)
 	}
 
+	for _, err := range keys.ApplyOverrides(appConfig.Keybindings) {
+		log.WarningLog.Printf("invalid keybinding in config: %v", err)
+	}
+
+	preview := ui.NewPreviewPane()
+	preview.SetMaxChars(appConfig.PreviewMaxChars)
+	wrapMode, err := ui.ParseWrapMode(appConfig.PreviewWrapMode)
+	if err != nil {
+		log.WarningLog.Printf("invalid preview wrap mode in config: %v", err)
+	}
+	preview.SetWrapMode(wrapMode)
+
 	h := &home{
-		ctx:          ctx,
-		spinner:      spinner.New(spinner.WithSpinner(spinner.MiniDot)),
-		menu:         ui.NewMenu(),
-		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
-		errBox:       ui.NewErrBox(),
-		storage:      storage,
-		program:      program,
-		autoYes:      autoYes,
-		state:        stateDefault,
+		ctx:            ctx,
+		appConfig:      appConfig,
+		spinner:        spinner.New(spinner.WithSpinner(spinner.MiniDot)),
+		menu:           ui.NewMenu(),
+		tabbedWindow:   ui.NewTabbedWindow(preview, ui.NewDiffPane()),
+		errBox:         ui.NewErrBox(),
+		statusBar:      ui.NewStatusBar(),
+		storage:        storage,
+		program:        program,
+		autoYes:        autoYes,
+		defaultPath:    defaultPath,
+		lastActivity:   make(map[*session.Instance]time.Time),
+		lastChecked:    make(map[*session.Instance]time.Time),
+		waitingForUser: make(map[*session.Instance]bool),
+		notified:       make(map[*session.Instance]bool),
+		needsAttention: make(map[*session.Instance]bool),
+		lastSound:      make(map[*session.Instance]time.Time),
+		diffFailing:    make(map[*session.Instance]bool),
+		state:          stateDefault,
 	}
 	h.list = ui.NewList(&h.spinner, autoYes)
+	sortMode, err := ui.ParseSortMode(appConfig.SortOrder)
+	if err != nil {
+		log.WarningLog.Printf("invalid sort order in config: %v", err)
+	}
+	// Restore the selection, tab and manual ordering from the last run.
+	uiState, err := storage.LoadUIState()
+	if err != nil {
+		log.WarningLog.Printf("could not load ui state: %v", err)
+	}
+	if uiState.ManualOrder {
+		sortMode = ui.SortManual
+	}
+	h.list.SetSortMode(sortMode)
+	h.list.SetHidePaused(uiState.HidePaused)
 
-	// Load saved instances
-	instances, err := storage.LoadInstances()
+	// Load saved instances. With lazy loading, their tmux sessions are restored once they're selected.
+	loadInstances := storage.LoadInstances
+	if appConfig.LazyLoad {
+		loadInstances = storage.LoadInstancesDeferred
+	}
+	instances, err := loadInstances()
 	if err != nil {
 		fmt.Printf("Failed to load instances: %v\n", err)
 		os.Exit(1)
@@ -104,10 +267,27 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 	for _, instance := range instances {
 		// Call the finalizer immediately.
 		h.list.AddInstance(instance)()
-		if autoYes {
-			instance.AutoYes = true
+	}
+
+	// If the selected instance is gone, we stay on the first one.
+	for idx, instance := range h.list.GetInstances() {
+		if instance.Title == uiState.SelectedTitle {
+			h.list.SetSelectedInstance(idx)
+			break
+		}
+	}
+	if uiState.Workspace != "" {
+		workspaces, err := storage.LoadWorkspaces()
+		if err != nil {
+			log.WarningLog.Printf("could not load workspaces: %v", err)
+		} else if titles, ok := workspaces[uiState.Workspace]; ok {
+			h.workspace = uiState.Workspace
+			h.list.SetWorkspace(h.workspace, titles)
 		}
 	}
+	if h.tabbedWindow.SetActiveTab(uiState.ActiveTab) {
+		h.menu.SetActiveTab(uiState.ActiveTab)
+	}
 
 	return h
 }
@@ -115,13 +295,14 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 // updateHandleWindowSizeEvent sets the sizes of the components.
 // The components will try to render inside their bounds.
 func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
-	// List takes 30% of width, preview takes 70%
-	listWidth := int(float32(msg.Width) * 0.3)
+	// List takes ListWidthPercent of width (30% by default), preview takes the rest
+	listWidth := msg.Width * clampListWidthPercent(m.appConfig.ListWidthPercent) / 100
 	tabsWidth := msg.Width - listWidth
 
 	// Menu takes 10% of height, list and window take 90%
 	contentHeight := int(float32(msg.Height) * 0.9)
-	menuHeight := msg.Height - contentHeight - 1 // minus 1 for error box
+	menuHeight := msg.Height - contentHeight - 2 // minus 1 for status bar and 1 for error box
+	m.statusBar.SetSize(msg.Width, 1)            // status bar takes 1 row
 	m.errBox.SetSize(msg.Width, 1)               // error box takes 1 row
 
 	m.tabbedWindow.SetSize(tabsWidth, contentHeight)
@@ -134,16 +315,41 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	m.menu.SetSize(msg.Width, menuHeight)
 }
 
+const listWidthStep = 5
+
+// clampListWidthPercent keeps the list width within bounds so neither the list nor the preview become unusable.
+func clampListWidthPercent(percent int) int {
+	return min(max(percent, config.MinListWidthPercent), config.MaxListWidthPercent)
+}
+
+// resizeList changes the list width by delta percent, saves it to the config and re-lays out the window.
+func (m *home) resizeList(delta int) (tea.Model, tea.Cmd) {
+	percent := clampListWidthPercent(clampListWidthPercent(m.appConfig.ListWidthPercent) + delta)
+	if percent == m.appConfig.ListWidthPercent {
+		return m, nil
+	}
+	m.appConfig.ListWidthPercent = percent
+	if err := config.SaveConfig(m.appConfig); err != nil {
+		log.WarningLog.Printf("could not save list width: %v", err)
+	}
+	return m, tea.WindowSize()
+}
+
+// selectTab switches the tabbed window to tab and updates the menu to match.
+func (m *home) selectTab(tab int) {
+	if m.tabbedWindow.SetActiveTab(tab) {
+		m.menu.SetActiveTab(tab)
+	}
+}
+
 func (m *home) Init() tea.Cmd {
 	// Upon starting, we want to start the spinner. Whenever we get a spinner.TickMsg, we
 	// update the spinner, which sends a new spinner.TickMsg. I think this lasts forever lol.
 	return tea.Batch(
 		m.spinner.Tick,
-		func() tea.Msg {
-			time.Sleep(100 * time.Millisecond)
-			return previewTickMsg{}
-		},
-		tickUpdateMetadataCmd,
+		m.tickPreviewCmd(),
+		m.tickUpdateMetadataCmd(),
+		m.tickAutoSaveCmd(),
 	)
 }
 
@@ -151,43 +357,115 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case hideErrMsg:
 		m.errBox.Clear()
+	case shutdownMsg:
+		// Quit even if saving fails, the process is being terminated anyway.
+		if err := m.saveState(); err != nil {
+			log.ErrorLog.Printf("could not save instances on shutdown: %v", err)
+		}
+		return m, tea.Quit
 	case previewTickMsg:
+		if m.state == stateLog {
+			m.logOverlay.SetContent(readLogTail())
+		}
+		if m.state == stateSummary {
+			m.summaryOverlay.SetContent(m.summary())
+		}
 		var cmd tea.Cmd
 		model, cmd := m.updatePreview()
 		m = model.(*home)
 		return m, tea.Batch(
 			cmd,
-			func() tea.Msg {
-				time.Sleep(100 * time.Millisecond)
-				return previewTickMsg{}
-			},
+			m.tickPreviewCmd(),
 		)
 	case keyupMsg:
 		m.menu.ClearKeydown()
 		return m, nil
+	case autoSaveTickMsg:
+		if m.autoSaving {
+			return m, m.tickAutoSaveCmd()
+		}
+		// Serialize the instances here, since they're only safe to read from Update, and write them in the
+		// background. The generation lets the write be skipped if the instances are saved again before it runs.
+		generation := m.storage.SaveGeneration()
+		data := make([]session.InstanceData, 0, m.list.NumInstances())
+		for _, instance := range m.list.GetInstances() {
+			if instance.Started() {
+				data = append(data, instance.ToInstanceData())
+			}
+		}
+		m.autoSaving = true
+		return m, tea.Batch(
+			func() tea.Msg {
+				return autoSaveDoneMsg{err: m.storage.AutoSaveInstanceData(data, generation)}
+			},
+			m.tickAutoSaveCmd(),
+		)
+	case autoSaveDoneMsg:
+		m.autoSaving = false
+		if msg.err != nil {
+			log.WarningLog.Printf("could not auto-save instances: %v", msg.err)
+		}
+		return m, nil
 	case tickUpdateMetadataMessage:
+		waiting := 0
 		for _, instance := range m.list.GetInstances() {
-			if !instance.Started() || instance.Paused() {
+			if !instance.Started() || instance.Paused() || instance.Deferred() {
+				continue
+			}
+			if !m.shouldCheck(instance) {
+				if m.waitingForUser[instance] {
+					waiting++
+				}
 				continue
 			}
+			m.lastChecked[instance] = time.Now()
 			updated, prompt := instance.HasUpdated()
+			// Prompts autoyes won't answer wait for the user like any other.
+			waitingForUser := prompt && (!m.autoYesFor(instance) || m.autoYesDenied(instance))
+			m.notifyIfWaiting(instance, waitingForUser)
+			if waitingForUser {
+				waiting++
+			}
+			m.waitingForUser[instance] = waitingForUser
+			m.needsAttention[instance] = waitingForUser
 			if updated {
 				instance.SetStatus(session.Running)
+				m.lastActivity[instance] = time.Now()
+				instance.UpdatedAt = time.Now()
 			} else {
 				if prompt {
-					instance.TapEnter()
+					if !waitingForUser {
+						instance.TapEnter()
+					}
 				} else {
+					if instance.Status == session.Running {
+						m.playSound(instance)
+					}
 					instance.SetStatus(session.Ready)
 				}
 			}
 			if err := instance.UpdateDiffStats(); err != nil {
 				log.WarningLog.Printf("could not update diff stats: %v", err)
+				m.needsAttention[instance] = true
+				if !m.diffFailing[instance] {
+					m.playSound(instance)
+				}
+				m.diffFailing[instance] = true
+			} else {
+				delete(m.diffFailing, instance)
+			}
+			if err := instance.UpdateHead(); err != nil {
+				log.WarningLog.Printf("could not read the branch of %s: %v", instance.Title, err)
 			}
+			m.autoPauseIfIdle(instance)
 		}
-		return m, tickUpdateMetadataCmd
+		m.statusBar.Update(m.list.GetInstances(), waiting)
+		// Statuses and activity times changed, so the order may have too.
+		m.list.Sort()
+		return m, m.tickUpdateMetadataCmd()
 	case tea.MouseMsg:
 		// Handle mouse wheel scrolling in the diff view
-		if m.tabbedWindow.IsInDiffTab() {
+		if m.tabbedWindow.GetActiveTab() == ui.DiffTab {
 			if msg.Action == tea.MouseActionPress {
 				switch msg.Button {
 				case tea.MouseButtonWheelUp:
@@ -200,11 +478,22 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		return m, nil
+	case instanceStartedMsg:
+		model, cmd := m.finishStartingInstance(msg.instance, msg.started, msg.err)
+		if m.quitAfterStart {
+			return m.handleQuit()
+		}
+		return model, cmd
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 	case tea.WindowSizeMsg:
 		m.updateHandleWindowSizeEvent(msg)
 		return m, nil
+	case editorFinishedMsg:
+		if msg.err != nil {
+			return m.showErrorMessageForShortTime(msg.err)
+		}
+		return m, tea.WindowSize()
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -214,16 +503,265 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *home) handleQuit() (tea.Model, tea.Cmd) {
+	if err := m.saveState(); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	return m, tea.Quit
+}
+
+// saveState saves the instances and the UI state. Only failing to save the instances is an error.
+func (m *home) saveState() error {
 	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return err
+	}
+	uiState := session.UIState{
+		ActiveTab:   m.tabbedWindow.GetActiveTab(),
+		ManualOrder: m.list.GetSortMode() == ui.SortManual,
+		HidePaused:  m.list.HidePaused(),
+		Workspace:   m.workspace,
+	}
+	if selected := m.list.GetSelectedInstance(); selected != nil {
+		uiState.SelectedTitle = selected.Title
+	}
+	if err := m.storage.SaveUIState(uiState); err != nil {
+		log.WarningLog.Printf("could not save ui state: %v", err)
+	}
+	return nil
+}
+
+// maxIdleCheckInterval is the longest time between checks of an idle instance's pane, so an instance which starts
+// working again is still noticed within a few seconds.
+const maxIdleCheckInterval = 3 * time.Second
+
+// shouldCheck returns whether the instance's pane should be checked on this metadata tick. Capturing panes is
+// expensive, so instances are checked less often the longer their output has been unchanged, up to
+// maxIdleCheckInterval. The selected instance is always checked.
+func (m *home) shouldCheck(instance *session.Instance) bool {
+	lastActivity, ok := m.lastActivity[instance]
+	if !ok {
+		// Start counting from the first time we see the instance.
+		m.lastActivity[instance] = time.Now()
+		return true
+	}
+	if instance == m.list.GetSelectedInstance() {
+		return true
+	}
+	interval := min(time.Since(lastActivity)/10, maxIdleCheckInterval)
+	return time.Since(m.lastChecked[instance]) >= interval
+}
+
+// autoYesFor returns whether autoyes answers the prompts of instance, because it's on for the whole app or was
+// turned on for the instance with KeyAutoYes. Only the latter is saved with the instance.
+func (m *home) autoYesFor(instance *session.Instance) bool {
+	return m.autoYes || instance.AutoYes
+}
+
+// autoYesDenied returns whether autoyes must leave the prompt shown by instance for the user, because the pane
+// matches one of the autoyes deny patterns.
+func (m *home) autoYesDenied(instance *session.Instance) bool {
+	pattern, denied := instance.AutoYesDenied()
+	// Only log when the prompt first shows up, not on every tick.
+	if denied && !m.needsAttention[instance] {
+		log.InfoLog.Printf("not answering prompt of %s in autoyes mode, it matches deny pattern %q", instance.Title, pattern)
+	}
+	return denied
+}
+
+// notifyIfWaiting sends a desktop notification when an instance starts waiting for input. Instances in autoyes
+// mode answer their own prompts, so they only notify if a deny pattern stopped them.
+func (m *home) notifyIfWaiting(instance *session.Instance, hasPrompt bool) {
+	if !hasPrompt {
+		delete(m.notified, instance)
+		return
+	}
+	if !m.appConfig.Notifications || m.notified[instance] {
+		return
+	}
+	m.notified[instance] = true
+	title := instance.Title
+	go func() {
+		if err := notify.Send("claude-squad", fmt.Sprintf("%s is waiting for your input", title)); err != nil {
+			log.WarningLog.Printf("could not send notification: %v", err)
+		}
+	}()
+}
+
+// soundCooldown is the minimum time between sounds for one instance. Output often pauses briefly
+// while an agent works, which flips it to ready and back.
+const soundCooldown = 30 * time.Second
+
+// playSound plays the sound for an instance which just finished working or failed.
+func (m *home) playSound(instance *session.Instance) {
+	if !m.appConfig.SoundOnComplete || time.Since(m.lastSound[instance]) < soundCooldown {
+		return
+	}
+	m.lastSound[instance] = time.Now()
+	command := m.appConfig.SoundCommand
+	go func() {
+		if err := notify.Sound(command); err != nil {
+			log.WarningLog.Printf("could not play sound: %v", err)
+		}
+	}()
+}
+
+const (
+	// logViewerLines is the number of lines of the log shown in the log viewer.
+	logViewerLines = 30
+	// logViewerWidth is the width log lines are cut to in the log viewer.
+	logViewerWidth = 120
+)
+
+// readLogTail returns the end of the log for the log viewer, or the error if it can't be read.
+func readLogTail() string {
+	tail, err := log.Tail(logViewerLines)
+	if err != nil {
+		return err.Error()
+	}
+	return tail
+}
+
+// summary returns the totals of the started instances for the summary overlay, followed by a line per instance.
+// Lines changed count every file, ignoring diff filters.
+func (m *home) summary() string {
+	var added, removed, pushes int
+	statusCounts := make(map[session.Status]int)
+	var rows []string
+	for _, instance := range m.list.GetInstances() {
+		if !instance.Started() {
+			continue
+		}
+		statusCounts[instance.Status]++
+		pushes += instance.Pushes
+		instanceAdded, instanceRemoved := 0, 0
+		if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+			instanceAdded, instanceRemoved = stats.TotalAdded, stats.TotalRemoved
+		}
+		added += instanceAdded
+		removed += instanceRemoved
+		rows = append(rows, fmt.Sprintf("%-*s %-8s +%-6d -%-6d %d pushed",
+			session.MaxTitleLength, instance.Title, instance.Status, instanceAdded, instanceRemoved, instance.Pushes))
+	}
+	if len(rows) == 0 {
+		return "No sessions"
+	}
+
+	var counts []string
+	for _, status := range []session.Status{session.Running, session.Ready, session.Loading, session.Paused} {
+		if statusCounts[status] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", statusCounts[status], status))
+		}
+	}
+	lines := []string{
+		fmt.Sprintf("Sessions:      %d (%s)", len(rows), strings.Join(counts, ", ")),
+		fmt.Sprintf("Lines changed: +%d -%d", added, removed),
+		fmt.Sprintf("Pushes:        %d", pushes),
+		"",
+	}
+	return strings.Join(append(lines, rows...), "\n")
+}
+
+// promptForProgram asks for a program and creates a new instance running it.
+func (m *home) promptForProgram() (tea.Model, tea.Cmd) {
+	return m.showTextInput("Program", m.program, false, func(value string) (tea.Model, tea.Cmd) {
+		program := strings.TrimSpace(value)
+		if program == "" {
+			return m.showErrorMessageForShortTime(fmt.Errorf("program cannot be empty"))
+		}
+		return m.newInstance(m.defaultPath, program, false)
+	})
+}
+
+// sendDefaultPrompt sends the configured default prompt template to a newly started instance, if there is one.
+func (m *home) sendDefaultPrompt(instance *session.Instance) error {
+	if m.appConfig.DefaultPromptTemplate == "" {
+		return nil
+	}
+	return instance.SendPromptTemplate(m.appConfig.DefaultPromptTemplate)
+}
+
+// editorFinishedMsg is sent when the editor opened by openEditor exits.
+type editorFinishedMsg struct {
+	err error
+}
+
+// openEditor suspends the TUI and runs the configured editor in the instance's worktree. The TUI is restored
+// once the editor exits.
+func (m *home) openEditor(instance *session.Instance) (tea.Model, tea.Cmd) {
+	path, err := instance.WorktreePath()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	editor, err := m.appConfig.GetEditor()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return m.showErrorMessageForShortTime(fmt.Errorf("editor command is empty"))
+	}
+	cmd := exec.Command(args[0], append(args[1:], ".")...)
+	cmd.Dir = path
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if err != nil {
+			err = fmt.Errorf("editor exited with error: %w", err)
+		}
+		return editorFinishedMsg{err: err}
+	})
+}
+
+// showSearchStatus refreshes the preview and shows which search match is selected.
+func (m *home) showSearchStatus() (tea.Model, tea.Cmd) {
+	if model, cmd := m.updatePreview(); cmd != nil {
+		return model, cmd
+	}
+	return m.showInfoMessageForShortTime(m.tabbedWindow.PreviewSearchStatus())
+}
+
+// killAllAndQuit kills every instance, removes them from storage and cleans up any leftover tmux sessions
+// before quitting. Unlike handleQuit, nothing is left to restore on the next start.
+func (m *home) killAllAndQuit() (tea.Model, tea.Cmd) {
+	for _, instance := range m.list.GetInstances() {
+		if err := instance.Kill(); err != nil {
+			log.ErrorLog.Printf("could not kill instance %s: %v", instance.Title, err)
+		}
+	}
+	if err := m.storage.DeleteAllInstances(); err != nil {
 		return m.showErrorMessageForShortTime(err)
 	}
+	if err := tmux.CleanupSessions(); err != nil {
+		log.ErrorLog.Printf("could not cleanup tmux sessions: %v", err)
+	}
 	return m, tea.Quit
 }
 
 func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
+	if m.state == stateStarting {
+		// Quit once the instance has started, so it's saved or cleaned up rather than left half created.
+		if name, ok := keys.GlobalKeyStringsMap[msg.String()]; msg.String() == "ctrl+c" || (ok && name == keys.KeyQuit) {
+			m.quitAfterStart = true
+			m.errBox.SetInfo("quitting once the session has started...")
+		}
+		return m, nil
+	}
+	// While searching the preview, n/N move between matches instead of creating instances.
+	if m.state == stateDefault && m.tabbedWindow.IsPreviewSearching() {
+		switch msg.String() {
+		case "n":
+			m.tabbedWindow.NextPreviewMatch()
+			return m.showSearchStatus()
+		case "N":
+			m.tabbedWindow.PrevPreviewMatch()
+			return m.showSearchStatus()
+		case "esc":
+			m.tabbedWindow.SearchPreview("")
+			return m.updatePreview()
+		}
+	}
+
 	// Handle menu highlighting when you press a button. We intercept it here and immediately return to
 	// update the ui while re-sending the keypress. Then, on the next call to this, we actually handle the keypress.
-	if !m.keySent && m.state != statePrompt {
+	if !m.keySent && m.state != statePrompt && m.state != stateTextInput && m.state != stateConfirm &&
+		m.state != stateSelect && m.state != stateLog && m.state != stateSummary {
 		// If it's in the global keymap, we should try to highlight it.
 		name, ok := keys.GlobalKeyStringsMap[msg.String()]
 		// Skip the menu highlighting if the key is not in the map or we are using the shift up and down keys.
@@ -256,36 +794,29 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				return m.showErrorMessageForShortTime(fmt.Errorf("title cannot be empty"))
 			}
 
-			if err := instance.Start(true); err != nil {
-				m.list.Kill()
-				m.state = stateDefault
-				return m.showErrorMessageForShortTime(err)
-			}
-			// Save after adding new instance
-			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
-				return m.showErrorMessageForShortTime(err)
+			// Ask which branch to start from if there's a choice. The current branch is listed first.
+			branches, _, err := git.ListBranches(instance.Path)
+			if err != nil {
+				log.WarningLog.Printf("could not list branches, starting from HEAD: %v", err)
 			}
-			// Instance added successfully, call the finalizer.
-			m.newInstanceFinalizer()
-			if m.autoYes {
-				instance.AutoYes = true
+			if len(branches) <= 1 {
+				return m.startNewInstance(instance)
 			}
-
-			m.newInstanceFinalizer()
-			m.state = stateDefault
-			if m.promptAfterName {
-				m.state = statePrompt
-				m.menu.SetState(ui.StatePrompt)
-				// Initialize the text input overlay
-				m.textInputOverlay = overlay.NewTextInputOverlay("Enter prompt", "")
-				m.promptAfterName = false
-			} else {
-				m.menu.SetState(ui.StateDefault)
+			model, cmd := m.selectItem("Base branch", branches, func(idx int) (tea.Model, tea.Cmd) {
+				instance.BaseBranch = branches[idx]
+				return m.startNewInstance(instance)
+			})
+			// Go back to editing the name if the user doesn't pick a branch.
+			m.selectOnCancel = func() (tea.Model, tea.Cmd) {
+				m.state = stateNew
+				m.menu.SetState(ui.StateNewInstance)
+				return m, tea.WindowSize()
 			}
-			return m, tea.WindowSize()
+			return model, cmd
 		case tea.KeyRunes:
-			if len(instance.Title) >= 32 {
-				return m.showErrorMessageForShortTime(fmt.Errorf("title cannot be longer than 32 characters"))
+			if utf8.RuneCountInString(instance.Title) >= session.MaxTitleLength {
+				return m.showErrorMessageForShortTime(
+					fmt.Errorf("title cannot be longer than %d characters", session.MaxTitleLength))
 			}
 			if err := instance.SetTitle(instance.Title + string(msg.Runes)); err != nil {
 				return m.showErrorMessageForShortTime(err)
@@ -329,6 +860,10 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
 					return m.showErrorMessageForShortTime(err)
 				}
+				// Remember the first prompt so it can be replayed when the instance is duplicated.
+				if selected.Prompt == "" {
+					selected.Prompt = m.textInputOverlay.GetValue()
+				}
 			}
 
 			// Close the overlay and reset state
@@ -344,133 +879,620 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 
 		return m, nil
+	} else if m.state == stateLog {
+		if m.logOverlay.HandleKeyPress(msg) {
+			m.logOverlay = nil
+			m.state = stateDefault
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	} else if m.state == stateSummary {
+		if m.summaryOverlay.HandleKeyPress(msg) {
+			m.summaryOverlay = nil
+			m.state = stateDefault
+			return m, tea.WindowSize()
+		}
+		return m, nil
+	} else if m.state == stateSelect {
+		shouldClose := m.selectionOverlay.HandleKeyPress(msg)
+		if !shouldClose {
+			return m, nil
+		}
+
+		chosen := m.selectionOverlay.IsChosen()
+		idx := m.selectionOverlay.Selected
+		onSelect := m.selectOnSelect
+		m.selectionOverlay = nil
+		onCancel := m.selectOnCancel
+		m.selectOnSelect = nil
+		m.selectOnCancel = nil
+		m.state = stateDefault
+		if !chosen {
+			if onCancel != nil {
+				return onCancel()
+			}
+			return m, tea.WindowSize()
+		}
+		return onSelect(idx)
+	} else if m.state == stateConfirm {
+		shouldClose := m.confirmationOverlay.HandleKeyPress(msg)
+		if !shouldClose {
+			return m, nil
+		}
+
+		confirmed := m.confirmationOverlay.IsConfirmed()
+		onConfirm := m.confirmOnConfirm
+		m.confirmationOverlay = nil
+		m.confirmOnConfirm = nil
+		m.state = stateDefault
+		if !confirmed {
+			return m, tea.WindowSize()
+		}
+		return onConfirm()
+	} else if m.state == stateTextInput {
+		shouldClose := m.textInputOverlay.HandleKeyPress(msg)
+		if !shouldClose {
+			return m, nil
+		}
+
+		submitted := m.textInputOverlay.IsSubmitted()
+		value := m.textInputOverlay.GetValue()
+		onSubmit := m.textInputOnSubmit
+		m.textInputOverlay = nil
+		m.textInputOnSubmit = nil
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		if !submitted {
+			return m, tea.WindowSize()
+		}
+		return onSubmit(value)
 	}
 
 	// Handle quit commands first
-	if msg.String() == "ctrl+c" || msg.String() == "q" {
+	if msg.String() == "ctrl+c" {
 		return m.handleQuit()
 	}
 
 	name, ok := keys.GlobalKeyStringsMap[msg.String()]
+	// Unbound digits are a count for the next jump key, like in less.
+	if s := msg.String(); !ok && len(s) == 1 && s[0] >= '0' && s[0] <= '9' {
+		m.jumpCount = min(m.jumpCount*10+int(s[0]-'0'), maxJumpCount)
+		return m, nil
+	}
+	count := m.jumpCount
+	m.jumpCount = 0
 	if !ok {
 		return m, nil
 	}
 
 	switch name {
+	case keys.KeyTop, keys.KeyBottom:
+		switch {
+		case count > 0:
+			m.tabbedWindow.JumpToLine(count)
+		case name == keys.KeyTop:
+			m.tabbedWindow.JumpToPercent(0)
+		default:
+			m.tabbedWindow.JumpToPercent(100)
+		}
+		return m.updatePreview()
+	case keys.KeyJumpPercent:
+		m.tabbedWindow.JumpToPercent(count)
+		return m.updatePreview()
+	case keys.KeyQuit:
+		return m.handleQuit()
+	case keys.KeyKillAllAndQuit:
+		return m.confirmAction("Kill all sessions and quit? This deletes all of their worktrees and branches.",
+			m.killAllAndQuit)
 	case keys.KeyPrompt:
-		if m.list.NumInstances() >= GlobalInstanceLimit {
-			return m.showErrorMessageForShortTime(
-				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
-		}
-		instance, err := session.NewInstance(session.InstanceOptions{
-			Title:   "",
-			Path:    ".",
-			Program: m.program,
-		})
-		if err != nil {
+		return m.newInstance(m.defaultPath, m.program, true)
+	case keys.KeyNew:
+		return m.newInstance(m.defaultPath, m.program, false)
+	case keys.KeyNewWithProgram:
+		if err := m.checkInstanceLimit(); err != nil {
 			return m.showErrorMessageForShortTime(err)
 		}
-
-		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
-		m.state = stateNew
-		m.menu.SetState(ui.StateNewInstance)
-		m.promptAfterName = true
-
-		return m, nil
-	case keys.KeyNew:
-		if m.list.NumInstances() >= GlobalInstanceLimit {
-			return m.showErrorMessageForShortTime(
-				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
-		}
-		instance, err := session.NewInstance(session.InstanceOptions{
-			Title:   "",
-			Path:    ".",
-			Program: m.program,
+		if len(m.appConfig.Profiles) == 0 {
+			return m.promptForProgram()
+		}
+		// Offer the profiles, with a last option to type in any other program.
+		names := m.appConfig.ProfileNames()
+		items := make([]string, 0, len(names)+1)
+		for _, name := range names {
+			items = append(items, fmt.Sprintf("%s: %s", name, m.appConfig.Profiles[name]))
+		}
+		items = append(items, "other program...")
+		return m.selectItem("Profile", items, func(idx int) (tea.Model, tea.Cmd) {
+			if idx == len(names) {
+				return m.promptForProgram()
+			}
+			return m.newInstance(m.defaultPath, m.appConfig.Profiles[names[idx]], false)
 		})
-		if err != nil {
+	case keys.KeyNewFromTemplate:
+		if err := m.checkInstanceLimit(); err != nil {
 			return m.showErrorMessageForShortTime(err)
 		}
-
-		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
-		m.state = stateNew
-		m.menu.SetState(ui.StateNewInstance)
-
-		return m, nil
-	case keys.KeyUp:
-		m.list.Up()
-		return m.updatePreview()
-	case keys.KeyDown:
-		m.list.Down()
-		return m.updatePreview()
-	case keys.KeyShiftUp:
-		if m.tabbedWindow.IsInDiffTab() {
-			m.tabbedWindow.ScrollUp()
+		names := m.appConfig.TemplateNames()
+		if len(names) == 0 {
+			return m.showErrorMessageForShortTime(fmt.Errorf("there are no templates in the config"))
 		}
-		return m.updatePreview()
-	case keys.KeyShiftDown:
-		if m.tabbedWindow.IsInDiffTab() {
-			m.tabbedWindow.ScrollDown()
+		return m.selectItem("Template", names, func(idx int) (tea.Model, tea.Cmd) {
+			return m.newInstanceFromTemplate(m.appConfig.Templates[names[idx]])
+		})
+	case keys.KeyNewInPath:
+		if err := m.checkInstanceLimit(); err != nil {
+			return m.showErrorMessageForShortTime(err)
 		}
-		return m.updatePreview()
-	case keys.KeyTab:
-		m.tabbedWindow.Toggle()
-		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
-		return m.updatePreview()
-	case keys.KeyKill:
+		return m.showTextInput("Repository path", m.defaultPath, false, func(value string) (tea.Model, tea.Cmd) {
+			repoRoot, err := git.FindRepoRoot(strings.TrimSpace(value))
+			if err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m.newInstance(repoRoot, m.program, false)
+		})
+	case keys.KeyBroadcast:
+		return m.showTextInput("Prompt all sessions", "", true, m.broadcastPrompt)
+	case keys.KeyDuplicate:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
 			return m, nil
 		}
-
-		// Delete from storage first
-		if err := m.storage.DeleteInstance(selected.Title); err != nil {
-			return m.showErrorMessageForShortTime(err)
+		return m.duplicateInstance(selected)
+	case keys.KeyLog:
+		m.state = stateLog
+		m.logOverlay = overlay.NewTextViewerOverlay(log.FilePath(), readLogTail(), logViewerWidth)
+		return m, nil
+	case keys.KeySummary:
+		m.state = stateSummary
+		m.summaryOverlay = overlay.NewTextViewerOverlay("Summary", m.summary(), logViewerWidth)
+		return m, nil
+	case keys.KeyPrune:
+		statuses := []session.Status{session.Paused, session.Ready}
+		items := make([]string, len(statuses))
+		for i, status := range statuses {
+			items[i] = status.String()
 		}
-
-		// Then kill the instance
-		m.list.Kill()
-		return m, tea.WindowSize()
-	case keys.KeySubmit:
+		return m.selectItem("Prune all sessions which are", items, func(idx int) (tea.Model, tea.Cmd) {
+			status := statuses[idx]
+			var matching []*session.Instance
+			for _, instance := range m.list.GetInstances() {
+				if instance.Started() && instance.Status == status {
+					matching = append(matching, instance)
+				}
+			}
+			if len(matching) == 0 {
+				return m.showInfoMessageForShortTime(fmt.Sprintf("no %s sessions to prune", status))
+			}
+			message := fmt.Sprintf("[!] Kill %d %s sessions and delete their worktrees?", len(matching), status)
+			return m.confirmAction(message, func() (tea.Model, tea.Cmd) {
+				return m.pruneInstances(matching)
+			})
+		})
+	case keys.KeyDiffFilter:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
 			return m, nil
 		}
-
-		// Default commit message with timestamp
-		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s", selected.Title, time.Now().Format(time.RFC822))
-		worktree, err := selected.GetGitWorktree()
-		if err != nil {
+		if len(m.appConfig.DiffFilters) == 0 {
+			return m.showInfoMessageForShortTime("no diff filters configured, add some to diff_filters in the config")
+		}
+		// Cycle through the presets, then back to showing every file.
+		presets := append([]string{""}, m.appConfig.DiffFilters...)
+		next := presets[(slices.Index(presets, selected.DiffFilter)+1)%len(presets)]
+		if err := selected.SetDiffFilter(next); err != nil {
 			return m.showErrorMessageForShortTime(err)
 		}
-		if err = worktree.PushChanges(commitMsg); err != nil {
+		if err := selected.UpdateDiffStats(); err != nil {
+			log.WarningLog.Printf("could not update diff stats: %v", err)
+		}
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
 			return m.showErrorMessageForShortTime(err)
 		}
-
-		return m, nil
-	case keys.KeyCheckout:
-		selected := m.list.GetSelectedInstance()
-		if selected == nil {
+		// updatePreview only returns a command to show an error.
+		if model, cmd := m.updatePreview(); cmd != nil {
+			return model, cmd
+		}
+		if next == "" {
+			return m.showInfoMessageForShortTime("showing all files in the diff")
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("diff filter: %s", next))
+	case keys.KeyMoveUp, keys.KeyMoveDown:
+		delta := 1
+		if name == keys.KeyMoveUp {
+			delta = -1
+		}
+		if !m.list.MoveSelected(delta) {
 			return m, nil
 		}
-		if err := selected.Pause(); err != nil {
+		if err := m.saveState(); err != nil {
 			return m.showErrorMessageForShortTime(err)
 		}
-		return m.updatePreview()
-	case keys.KeyResume:
+		return m, nil
+	case keys.KeySendKey:
 		selected := m.list.GetSelectedInstance()
-		if selected == nil {
+		if selected == nil || !selected.Started() || selected.Paused() {
 			return m, nil
 		}
-		if err := selected.Resume(); err != nil {
-			return m.showErrorMessageForShortTime(err)
+		items := make([]string, len(sendableKeys))
+		for i, k := range sendableKeys {
+			items[i] = k.name
 		}
-		return m, tea.WindowSize()
-	case keys.KeyEnter:
+		return m.selectItem(fmt.Sprintf("Send key to %s", selected.Title), items, func(idx int) (tea.Model, tea.Cmd) {
+			if err := selected.SendKeys(sendableKeys[idx].sequence); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m.showInfoMessageForShortTime(fmt.Sprintf("sent %s to %s", sendableKeys[idx].name, selected.Title))
+		})
+	case keys.KeyMarkSeen:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		if selected.DiffBaseline != "" {
+			if err := selected.ClearDiffBaseline(); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m.showInfoMessageForShortTime("showing all changes again")
+		}
+		if err := selected.MarkDiffSeen(); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		return m.showInfoMessageForShortTime("changes marked as seen, only new changes are shown")
+	case keys.KeyNextAttention:
+		instances := m.list.GetInstances()
+		current := slices.Index(instances, m.list.GetSelectedInstance())
+		// Start after the selected instance and wrap around, ending with the selected one.
+		for offset := 1; offset <= len(instances); offset++ {
+			idx := (current + offset) % len(instances)
+			if m.needsAttention[instances[idx]] && !instances[idx].Paused() {
+				m.list.SetSelectedInstance(idx)
+				return m.updatePreview()
+			}
+		}
+		return m.showInfoMessageForShortTime("no sessions need attention")
+	case keys.KeySort:
+		m.list.SetSortMode(m.list.GetSortMode().Next())
+		return m.showInfoMessageForShortTime(fmt.Sprintf("sorting by %s", m.list.GetSortMode()))
+	case keys.KeyAutoYes:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		if m.autoYes {
+			return m.showInfoMessageForShortTime("autoyes is on for every session")
+		}
+		selected.AutoYes = !selected.AutoYes
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if selected.AutoYes {
+			return m.showInfoMessageForShortTime(fmt.Sprintf("autoyes on for %s", selected.Title))
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("autoyes off for %s", selected.Title))
+	case keys.KeyWorkspace:
+		workspaces, err := m.storage.LoadWorkspaces()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if len(workspaces) == 0 {
+			return m.showErrorMessageForShortTime(fmt.Errorf("there are no saved workspaces"))
+		}
+		names := slices.Sorted(maps.Keys(workspaces))
+		items := append([]string{allSessionsItem}, names...)
+		return m.selectItem("Workspace", items, func(idx int) (tea.Model, tea.Cmd) {
+			if idx == 0 {
+				m.workspace = ""
+				m.list.SetWorkspace("", nil)
+				if err := m.saveState(); err != nil {
+					return m.showErrorMessageForShortTime(err)
+				}
+				return m.showInfoMessageForShortTime("showing all sessions")
+			}
+			return m.switchWorkspace(names[idx-1], workspaces[names[idx-1]])
+		})
+	case keys.KeySaveWorkspace:
+		return m.showTextInput("Workspace name", m.workspace, false, m.saveWorkspace)
+	case keys.KeyHidePaused:
+		m.list.SetHidePaused(!m.list.HidePaused())
+		if err := m.saveState(); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if m.list.HidePaused() {
+			return m.showInfoMessageForShortTime("hiding paused sessions")
+		}
+		return m.showInfoMessageForShortTime("showing paused sessions")
+	case keys.KeyPin:
+		if m.list.GetSelectedInstance() == nil {
+			return m, nil
+		}
+		m.list.TogglePinSelected()
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		return m.updatePreview()
+	case keys.KeyPromptHistory:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if len(selected.PromptHistory) == 0 {
+			return m.showInfoMessageForShortTime("no prompts have been sent to this session yet")
+		}
+		// Show the most recent prompt first.
+		history := make([]string, len(selected.PromptHistory))
+		for i, prompt := range selected.PromptHistory {
+			history[len(history)-1-i] = prompt
+		}
+		return m.selectItem("Re-send a prompt", history, func(idx int) (tea.Model, tea.Cmd) {
+			if err := selected.SendPrompt(history[idx]); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m, tea.WindowSize()
+		})
+	case keys.KeyRestart:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m.confirmAction(fmt.Sprintf("Restart %s in session '%s'?", selected.Program, selected.Title),
+			func() (tea.Model, tea.Cmd) {
+				if err := selected.Restart(); err != nil {
+					return m.showErrorMessageForShortTime(err)
+				}
+				return m.showInfoMessageForShortTime(fmt.Sprintf("restarted %s", selected.Title))
+			})
+	case keys.KeyTags:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m.showTextInput("Tags (comma separated)", strings.Join(selected.Tags, ", "), false,
+			func(value string) (tea.Model, tea.Cmd) {
+				selected.Tags = session.ParseTags(value)
+				if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+					return m.showErrorMessageForShortTime(err)
+				}
+				return m, tea.WindowSize()
+			})
+	case keys.KeyDescription:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m.showTextInput("Description", selected.Description, true, func(value string) (tea.Model, tea.Cmd) {
+			selected.Description = strings.TrimSpace(value)
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m, tea.WindowSize()
+		})
+	case keys.KeyRename:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m.showTextInput("Rename session", selected.Title, false, func(value string) (tea.Model, tea.Cmd) {
+			if err := m.renameSelected(strings.TrimSpace(value)); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m, tea.WindowSize()
+		})
+	case keys.KeyUp:
+		m.list.Up()
+		return m.updatePreview()
+	case keys.KeyDown:
+		m.list.Down()
+		return m.updatePreview()
+	case keys.KeyShiftUp:
+		m.tabbedWindow.ScrollUp()
+		return m.updatePreview()
+	case keys.KeyShiftDown:
+		m.tabbedWindow.ScrollDown()
+		return m.updatePreview()
+	case keys.KeyShrinkList:
+		return m.resizeList(-listWidthStep)
+	case keys.KeyGrowList:
+		return m.resizeList(listWidthStep)
+	case keys.KeyLowPower:
+		m.lowPower = !m.lowPower
+		if m.lowPower {
+			return m.showInfoMessageForShortTime("low power mode enabled")
+		}
+		return m.showInfoMessageForShortTime("low power mode disabled")
+	case keys.KeySearch:
+		if m.list.GetSelectedInstance() == nil {
+			return m, nil
+		}
+		return m.showTextInput("Search preview", "", false, func(value string) (tea.Model, tea.Cmd) {
+			m.selectTab(ui.PreviewTab)
+			m.tabbedWindow.SearchPreview(value)
+			if value == "" {
+				return m.updatePreview()
+			}
+			return m.showSearchStatus()
+		})
+	case keys.KeyFollow:
+		m.tabbedWindow.TogglePreviewFollowMode()
+		return m.updatePreview()
+	case keys.KeyTab:
+		m.tabbedWindow.NextTab()
+		m.menu.SetActiveTab(m.tabbedWindow.GetActiveTab())
+		return m.updatePreview()
+	case keys.KeyPreviewTab:
+		m.selectTab(ui.PreviewTab)
+		return m.updatePreview()
+	case keys.KeyDiffTab:
+		m.selectTab(ui.DiffTab)
+		return m.updatePreview()
+	case keys.KeyChangedFiles:
+		m.tabbedWindow.ToggleChangedFiles()
+		m.menu.SetActiveTab(m.tabbedWindow.GetActiveTab())
+		return m.updatePreview()
+	case keys.KeyKill:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if !m.appConfig.ConfirmKill {
+			return m.killSelected()
+		}
+		return m.confirmAction(fmt.Sprintf("Kill session '%s'? This deletes its worktree and branch.", selected.Title),
+			m.killSelected)
+	case keys.KeyKillKeepWorktree:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if !m.appConfig.ConfirmKill {
+			return m.killSelectedKeepWorktree()
+		}
+		return m.confirmAction(fmt.Sprintf("Kill session '%s' and keep its worktree and branch?", selected.Title),
+			m.killSelectedKeepWorktree)
+	case keys.KeyMerge:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		target, err := selected.MergeTarget()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		rebase := strings.EqualFold(m.appConfig.MergeStrategy, "rebase")
+		message := fmt.Sprintf("Merge session '%s' into %s? Its changes are committed first.", selected.Title, target)
+		if rebase {
+			message = fmt.Sprintf("Rebase session '%s' onto %s and fast-forward %s? Its changes are committed first.",
+				selected.Title, target, target)
+		}
+		return m.confirmAction(message, func() (tea.Model, tea.Cmd) {
+			base, err := selected.Merge(rebase)
+			if err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			if rebase {
+				return m.showInfoMessageForShortTime(fmt.Sprintf("rebased %s onto %s and fast-forwarded %s",
+					selected.Branch, base, base))
+			}
+			return m.showInfoMessageForShortTime(fmt.Sprintf("merged %s into %s", selected.Branch, base))
+		})
+	case keys.KeySubmit:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+
+		// Default commit message with timestamp
+		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s", selected.Title, time.Now().Format(time.RFC822))
+		// Keep the default message in autoyes mode so nothing waits on input.
+		if m.autoYes {
+			return m.pushChanges(selected, commitMsg)
+		}
+		return m.showTextInput("Commit message", commitMsg, true, func(value string) (tea.Model, tea.Cmd) {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return m.showErrorMessageForShortTime(fmt.Errorf("commit message cannot be empty"))
+			}
+			if m.appConfig.SubmitDryRun {
+				return m.confirmPush(selected, value)
+			}
+			return m.pushChanges(selected, value)
+		})
+	case keys.KeyExportDiff:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		dir, err := m.appConfig.GetDiffExportDir()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		path, err := selected.ExportDiff(dir, m.appConfig.CopyDiffToClipboard)
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("diff written to %s", path))
+	case keys.KeyCheckout:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if err := selected.Pause(); err != nil {
+			// With auto_commit_on_pause, the instance is paused even if committing failed.
+			if selected.Paused() {
+				_ = clipboard.WriteAll(selected.Branch)
+			}
+			return m.showErrorMessageForShortTime(err)
+		}
+		if model, cmd := m.updatePreview(); cmd != nil {
+			return model, cmd
+		}
+		if err := clipboard.WriteAll(selected.Branch); err != nil {
+			return m, nil
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("branch %s copied to your clipboard", selected.Branch))
+	case keys.KeyOpenEditor:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m.openEditor(selected)
+	case keys.KeyCopyPath:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		path, err := selected.WorktreePath()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if err := clipboard.WriteAll(path); err != nil {
+			return m.showErrorMessageForShortTime(fmt.Errorf("could not copy worktree path: %w", err))
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("copied %s", path))
+	case keys.KeyCopyAttachCommand:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		command, err := selected.AttachCommand()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		if err := clipboard.WriteAll(command); err != nil {
+			return m.showErrorMessageForShortTime(fmt.Errorf("could not copy attach command: %w", err))
+		}
+		return m.showInfoMessageForShortTime(fmt.Sprintf("copied %s", command))
+	case keys.KeyResume:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if err := selected.Resume(); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		return m, tea.WindowSize()
+	case keys.KeyEnter:
 		if m.list.NumInstances() == 0 {
 			return m, nil
 		}
+		if m.appConfig.TerminalCommand != "" {
+			selected := m.list.GetSelectedInstance()
+			if selected == nil {
+				return m, nil
+			}
+			if err := selected.AttachInTerminal(m.appConfig.TerminalCommand); err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m.showInfoMessageForShortTime(fmt.Sprintf("opened %s in a new window", selected.Title))
+		}
 		ch, err := m.list.Attach()
 		if err != nil {
 			return m.showErrorMessageForShortTime(err)
@@ -478,14 +1500,556 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		<-ch
 		// WindowSize clears the screen.
 		return m, tea.WindowSize()
+	case keys.KeyAttachReadOnly:
+		if m.list.NumInstances() == 0 {
+			return m, nil
+		}
+		ch, err := m.list.AttachReadOnly()
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		<-ch
+		return m, tea.WindowSize()
 	default:
 		return m, nil
 	}
 }
 
+// autoPauseIfIdle pauses the instance if it has been ready without any output changes for longer than the
+// configured idle duration.
+func (m *home) autoPauseIfIdle(instance *session.Instance) {
+	if m.appConfig.AutoPauseIdleMinutes <= 0 || instance.Status != session.Ready {
+		return
+	}
+	lastActivity, ok := m.lastActivity[instance]
+	if !ok {
+		// Start counting from the first time we see the instance.
+		m.lastActivity[instance] = time.Now()
+		return
+	}
+	if time.Since(lastActivity) < time.Duration(m.appConfig.AutoPauseIdleMinutes)*time.Minute {
+		return
+	}
+
+	log.InfoLog.Printf("auto-pausing idle instance %s", instance.Title)
+	// Nobody asked for the branch to be pushed, so only commit.
+	if err := instance.PauseWithoutPush(); err != nil {
+		log.ErrorLog.Printf("could not auto-pause instance %s: %v", instance.Title, err)
+	}
+	delete(m.lastActivity, instance)
+}
+
+// killSelected deletes the selected instance from storage and kills it.
+func (m *home) killSelected() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return m, nil
+	}
+
+	// Delete from storage first
+	if err := m.storage.DeleteInstance(selected.Title); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+
+	// Then kill the instance
+	m.list.Kill()
+	m.forgetInstance(selected)
+	return m, tea.WindowSize()
+}
+
+// killSelectedKeepWorktree stops the selected instance and removes it from the list and storage, but leaves its
+// worktree and branch for the user to review.
+func (m *home) killSelectedKeepWorktree() (tea.Model, tea.Cmd) {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil {
+		return m, nil
+	}
+	path, err := selected.KillKeepWorktree()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	m.list.Remove(selected)
+	m.forgetInstance(selected)
+	if err := m.storage.DeleteInstances([]string{selected.Title}); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	model, cmd := m.showInfoMessageForShortTime(fmt.Sprintf("killed %s, its worktree is kept at %s", selected.Title, path))
+	return model, tea.Batch(cmd, tea.WindowSize())
+}
+
+// forgetInstance drops the state tracked for an instance which was removed from the list.
+func (m *home) forgetInstance(instance *session.Instance) {
+	delete(m.lastActivity, instance)
+	delete(m.lastChecked, instance)
+	delete(m.waitingForUser, instance)
+	delete(m.notified, instance)
+	delete(m.needsAttention, instance)
+	delete(m.lastSound, instance)
+	delete(m.diffFailing, instance)
+}
+
+// pruneInstances kills instances and removes them from the list and storage. Instances which fail to die are
+// removed anyway, like killSelected does, and the failures are reported.
+func (m *home) pruneInstances(instances []*session.Instance) (tea.Model, tea.Cmd) {
+	titles := make([]string, len(instances))
+	var failed []string
+	for i, instance := range instances {
+		titles[i] = instance.Title
+		if err := instance.Kill(); err != nil {
+			log.ErrorLog.Printf("could not kill instance %s: %v", instance.Title, err)
+			failed = append(failed, instance.Title)
+		}
+		m.list.Remove(instance)
+		m.forgetInstance(instance)
+	}
+	if err := m.storage.DeleteInstances(titles); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+
+	if len(failed) > 0 {
+		return m.showErrorMessageForShortTime(fmt.Errorf("pruned %d sessions, but cleaning up %s failed, see the log",
+			len(instances), strings.Join(failed, ", ")))
+	}
+	model, cmd := m.showInfoMessageForShortTime(fmt.Sprintf("pruned %d sessions", len(instances)))
+	return model, tea.Batch(cmd, tea.WindowSize())
+}
+
+// selectItem shows a selection overlay and calls onSelect with the index of the item the user picks.
+func (m *home) selectItem(title string, items []string, onSelect func(idx int) (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	m.state = stateSelect
+	m.selectionOverlay = overlay.NewSelectionOverlay(title, items)
+	m.selectOnSelect = onSelect
+	m.selectOnCancel = nil
+	return m, nil
+}
+
+// confirmAction shows a confirmation overlay with message. onConfirm is called if the user confirms.
+func (m *home) confirmAction(message string, onConfirm func() (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	m.state = stateConfirm
+	m.confirmationOverlay = overlay.NewConfirmationOverlay(message)
+	m.confirmOnConfirm = onConfirm
+	return m, nil
+}
+
+// showTextInput opens a text input overlay. onSubmit is called with the value when the overlay is submitted. It's
+// not called if the overlay is canceled.
+func (m *home) showTextInput(
+	title, initialValue string, multiline bool, onSubmit func(value string) (tea.Model, tea.Cmd),
+) (tea.Model, tea.Cmd) {
+	m.state = stateTextInput
+	m.menu.SetState(ui.StatePrompt)
+	m.textInputOverlay = overlay.NewTextInputOverlay(title, initialValue)
+	m.textInputOverlay.Multiline = multiline
+	m.textInputOnSubmit = onSubmit
+	return m, nil
+}
+
+// sendableKeys are the keys which can be sent to a session from the list, and the bytes a terminal sends for them.
+var sendableKeys = []struct {
+	name     string
+	sequence string
+}{
+	{"ctrl+c (interrupt)", "\x03"},
+	{"esc", "\x1b"},
+	{"enter", "\r"},
+	{"up", "\x1b[A"},
+	{"down", "\x1b[B"},
+	{"tab", "\t"},
+	{"shift+tab", "\x1b[Z"},
+}
+
+// maxPushPlanFiles is the number of changed files listed before confirming a push.
+const maxPushPlanFiles = 15
+
+// confirmPush shows what pushing the instance would do, the branch, commit message and changed files, and only
+// pushes once it's confirmed.
+func (m *home) confirmPush(instance *session.Instance, commitMsg string) (tea.Model, tea.Cmd) {
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	isDirty, err := worktree.IsDirty()
+	if err != nil {
+		return m.showErrorMessageForShortTime(fmt.Errorf("failed to check for changes: %w", err))
+	}
+	files, err := worktree.ChangedFiles()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+
+	lines := []string{fmt.Sprintf("Push branch %s to origin?", worktree.GetBranchName()), ""}
+	if isDirty {
+		lines = append(lines, "Commit message:")
+		for _, line := range strings.Split(commitMsg, "\n") {
+			lines = append(lines, "  "+line)
+		}
+	} else {
+		lines = append(lines, "Nothing to commit, only existing commits are pushed.")
+	}
+	lines = append(lines, "", fmt.Sprintf("Changed files (%d):", len(files)))
+	for idx, file := range files {
+		if idx == maxPushPlanFiles {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(files)-maxPushPlanFiles))
+			break
+		}
+		lines = append(lines, "  "+strings.Replace(file, "\t", " ", 1))
+	}
+	return m.confirmAction(strings.Join(lines, "\n"), func() (tea.Model, tea.Cmd) {
+		return m.pushChanges(instance, commitMsg)
+	})
+}
+
+// pushChanges commits any changes in the instance's worktree with commitMsg and pushes the branch.
+func (m *home) pushChanges(instance *session.Instance, commitMsg string) (tea.Model, tea.Cmd) {
+	worktree, err := instance.GetGitWorktree()
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	if err = instance.PushChanges(commitMsg); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	// Nothing can answer the prompts in autoyes mode, so only push.
+	if !m.appConfig.CreatePROnSubmit || m.autoYes {
+		return m.showInfoMessageForShortTime(fmt.Sprintf("pushed branch %s", worktree.GetBranchName()))
+	}
+
+	title, _, _ := strings.Cut(commitMsg, "\n")
+	return m.showTextInput("Pull request title", title, false, func(title string) (tea.Model, tea.Cmd) {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return m.showErrorMessageForShortTime(fmt.Errorf("pull request title cannot be empty"))
+		}
+		return m.showTextInput("Pull request body", "", true, func(body string) (tea.Model, tea.Cmd) {
+			url, err := worktree.CreatePullRequest(title, strings.TrimSpace(body))
+			if err != nil {
+				return m.showErrorMessageForShortTime(err)
+			}
+			return m.showInfoMessageForShortTime(fmt.Sprintf("opened pull request %s", url))
+		})
+	})
+}
+
+// newInstance adds a new, unnamed instance running program for the repository at path to the list and enters
+// the naming state. If promptAfterName is true, the prompt overlay is shown once the instance is named.
+func (m *home) newInstance(path string, program string, promptAfterName bool) (tea.Model, tea.Cmd) {
+	if err := m.checkInstanceLimit(); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   "",
+		Path:    path,
+		Program: program,
+	})
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+
+	m.newInstanceFinalizer = m.list.AddInstance(instance)
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	m.state = stateNew
+	m.menu.SetState(ui.StateNewInstance)
+	m.promptAfterName = promptAfterName
+
+	return m, nil
+}
+
+// newInstanceFromTemplate is like newInstance, but the program, repository, tags and prompt come from the
+// template. The prompt is sent once the instance is named and started.
+func (m *home) newInstanceFromTemplate(template config.Template) (tea.Model, tea.Cmd) {
+	path := m.defaultPath
+	if template.Path != "" {
+		repoRoot, err := git.FindRepoRoot(template.Path)
+		if err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+		path = repoRoot
+	}
+	program := m.program
+	if template.Program != "" {
+		program = template.Program
+	}
+
+	model, cmd := m.newInstance(path, program, false)
+	if m.state != stateNew {
+		return model, cmd
+	}
+	instance := m.list.GetInstances()[m.list.NumInstances()-1]
+	instance.Tags = template.Tags
+	instance.Prompt = template.Prompt
+	return model, cmd
+}
+
+// startNewInstance starts the instance being created in stateNew in the background, since creating the worktree
+// can take a while in big repositories. The instance shows as loading until finishStartingInstance is called.
+func (m *home) startNewInstance(instance *session.Instance) (tea.Model, tea.Cmd) {
+	instance.SetStatus(session.Loading)
+	m.state = stateStarting
+	m.menu.SetState(ui.StateDefault)
+	m.errBox.SetInfo(fmt.Sprintf("creating worktree for %s...", instance.Title))
+	// Start a copy, since the UI reads the listed instance while this runs in the background.
+	// finishStartingInstance swaps the started copy in.
+	starting := *instance
+	return m, func() tea.Msg {
+		err := starting.Start(true)
+		return instanceStartedMsg{instance: instance, started: &starting, err: err}
+	}
+}
+
+// finishStartingInstance replaces the listed instance with the copy started by startNewInstance, saves it and
+// sends its first prompts. If promptAfterName is set, the prompt overlay is opened next. If starting failed, the
+// instance is removed.
+func (m *home) finishStartingInstance(instance, started *session.Instance, err error) (tea.Model, tea.Cmd) {
+	m.errBox.Clear()
+	m.state = stateDefault
+	if err != nil {
+		m.playSound(instance)
+		m.forgetInstance(instance)
+		m.list.Remove(instance)
+		m.promptAfterName = false
+		model, cmd := m.showErrorMessageForShortTime(err)
+		return model, tea.Batch(cmd, tea.WindowSize())
+	}
+	*instance = *started
+	m.addToWorkspace(instance)
+	// Save after adding new instance
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	// Instance added successfully, call the finalizer.
+	m.newInstanceFinalizer()
+	if err := m.sendDefaultPrompt(instance); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	// Instances created from a template already have their first prompt.
+	if instance.Prompt != "" {
+		if err := instance.SendPrompt(instance.Prompt); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+	}
+
+	m.newInstanceFinalizer()
+	if m.promptAfterName {
+		m.state = statePrompt
+		m.menu.SetState(ui.StatePrompt)
+		// Initialize the text input overlay
+		m.textInputOverlay = overlay.NewTextInputOverlay("Enter prompt", "")
+		m.promptAfterName = false
+	} else {
+		m.menu.SetState(ui.StateDefault)
+	}
+	return m, tea.WindowSize()
+}
+
+// maxJumpCount caps the count typed before a jump key, so it can't overflow.
+const maxJumpCount = 1_000_000
+
+// allSessionsItem is the first item when picking a workspace, which shows every session without pausing or
+// resuming any.
+const allSessionsItem = "All sessions"
+
+// switchWorkspace pauses the running instances which aren't in the workspace, resumes the paused ones which are,
+// and only shows the workspace's instances. Failures don't stop the remaining instances from being switched.
+func (m *home) switchWorkspace(name string, titles []string) (tea.Model, tea.Cmd) {
+	var errs []error
+	for _, instance := range m.list.GetInstances() {
+		if !instance.Started() {
+			continue
+		}
+		inWorkspace := slices.Contains(titles, instance.Title)
+		if inWorkspace && instance.Paused() {
+			if err := instance.Resume(); err != nil {
+				errs = append(errs, fmt.Errorf("could not resume %s: %w", instance.Title, err))
+			}
+		} else if !inWorkspace && !instance.Paused() {
+			if err := instance.Pause(); err != nil {
+				errs = append(errs, fmt.Errorf("could not pause %s: %w", instance.Title, err))
+			}
+		}
+	}
+	m.workspace = name
+	m.list.SetWorkspace(name, titles)
+	if err := m.saveState(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		model, cmd := m.showErrorMessageForShortTime(err)
+		return model, tea.Batch(cmd, tea.WindowSize())
+	}
+	model, cmd := m.showInfoMessageForShortTime(fmt.Sprintf("switched to workspace %s", name))
+	// WindowSize sizes the previews of the resumed instances.
+	return model, tea.Batch(cmd, tea.WindowSize())
+}
+
+// saveWorkspace saves the started instances shown as a workspace and switches to it.
+func (m *home) saveWorkspace(name string) (tea.Model, tea.Cmd) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return m.showErrorMessageForShortTime(fmt.Errorf("workspace name cannot be empty"))
+	}
+	var titles []string
+	for _, instance := range m.list.GetInstances() {
+		if instance.Started() && m.list.InWorkspace(instance) {
+			titles = append(titles, instance.Title)
+		}
+	}
+	if err := m.storage.SaveWorkspace(name, titles); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	m.workspace = name
+	m.list.SetWorkspace(name, titles)
+	if err := m.saveState(); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	return m.showInfoMessageForShortTime(fmt.Sprintf("saved workspace %s with %d sessions", name, len(titles)))
+}
+
+// addToWorkspace adds a new instance to the workspace shown, so it isn't hidden once it's started.
+func (m *home) addToWorkspace(instance *session.Instance) {
+	if m.workspace == "" {
+		return
+	}
+	workspaces, err := m.storage.LoadWorkspaces()
+	if err != nil {
+		log.WarningLog.Printf("could not add %s to workspace %s: %v", instance.Title, m.workspace, err)
+		return
+	}
+	titles := append(workspaces[m.workspace], instance.Title)
+	if err := m.storage.SaveWorkspace(m.workspace, titles); err != nil {
+		log.WarningLog.Printf("could not add %s to workspace %s: %v", instance.Title, m.workspace, err)
+	}
+	m.list.SetWorkspace(m.workspace, titles)
+}
+
+// broadcastPrompt sends prompt to every running instance. Paused instances are skipped, and failures don't stop
+// the prompt from being sent to the remaining instances.
+func (m *home) broadcastPrompt(prompt string) (tea.Model, tea.Cmd) {
+	if strings.TrimSpace(prompt) == "" {
+		return m.showErrorMessageForShortTime(fmt.Errorf("prompt cannot be empty"))
+	}
+
+	sent := 0
+	var failed []string
+	for _, instance := range m.list.GetInstances() {
+		if !instance.Started() || instance.Paused() {
+			continue
+		}
+		if err := instance.SendPrompt(prompt); err != nil {
+			log.ErrorLog.Printf("could not send prompt to %s: %v", instance.Title, err)
+			failed = append(failed, instance.Title)
+			continue
+		}
+		sent++
+	}
+
+	if len(failed) > 0 {
+		return m.showErrorMessageForShortTime(fmt.Errorf("sent prompt to %d sessions, failed for: %s",
+			sent, strings.Join(failed, ", ")))
+	}
+	return m.showInfoMessageForShortTime(fmt.Sprintf("sent prompt to %d sessions", sent))
+}
+
+// duplicateInstance starts a copy of original in a fresh worktree, running the same program in the same
+// repository. The original prompt is replayed if there was one.
+func (m *home) duplicateInstance(original *session.Instance) (tea.Model, tea.Cmd) {
+	if err := m.checkInstanceLimit(); err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   m.uniqueTitle(original.Title, "-copy"),
+		Path:    original.Path,
+		Program: original.Program,
+	})
+	if err != nil {
+		return m.showErrorMessageForShortTime(err)
+	}
+	// Start off the same base branch as the original, rather than whatever the repository has checked out now.
+	instance.BaseBranch = original.BaseBranch
+	if worktree, err := original.GetGitWorktree(); err == nil && worktree.GetBaseBranch() != "" {
+		instance.BaseBranch = worktree.GetBaseBranch()
+	}
+	instance.Tags = append([]string(nil), original.Tags...)
+	// finishStartingInstance sends it once the copy has started.
+	instance.Prompt = original.Prompt
+
+	m.newInstanceFinalizer = m.list.AddInstance(instance)
+	m.list.SetSelectedInstance(m.list.NumInstances() - 1)
+	return m.startNewInstance(instance)
+}
+
+// uniqueTitle returns base+suffix, adding an incrementing number if the title is taken. The base is shortened
+// so the title fits within session.MaxTitleLength.
+func (m *home) uniqueTitle(base, suffix string) string {
+	taken := make(map[string]bool)
+	for _, instance := range m.list.GetInstances() {
+		taken[instance.Title] = true
+	}
+	for n := 1; ; n++ {
+		s := suffix
+		if n > 1 {
+			s = fmt.Sprintf("%s-%d", suffix, n)
+		}
+		// Titles are limited in characters, so cut whole runes.
+		b := []rune(base)
+		if suffixLen := utf8.RuneCountInString(s); len(b)+suffixLen > session.MaxTitleLength {
+			b = b[:max(session.MaxTitleLength-suffixLen, 0)]
+		}
+		if title := string(b) + s; !taken[title] {
+			return title
+		}
+	}
+}
+
+// renameSelected renames the selected instance and persists the change. Titles are used as keys for tmux
+// sessions and storage, so they must be unique.
+func (m *home) renameSelected(title string) error {
+	selected := m.list.GetSelectedInstance()
+	if selected == nil || title == selected.Title {
+		return nil
+	}
+	if len(title) == 0 {
+		return fmt.Errorf("title cannot be empty")
+	}
+	if utf8.RuneCountInString(title) > session.MaxTitleLength {
+		return fmt.Errorf("title cannot be longer than %d characters", session.MaxTitleLength)
+	}
+	for _, instance := range m.list.GetInstances() {
+		if instance != selected && instance.Title == title {
+			return fmt.Errorf("a session named '%s' already exists", title)
+		}
+	}
+
+	oldTitle := selected.Title
+	if err := selected.Rename(title); err != nil {
+		return err
+	}
+	if err := m.storage.RenameInWorkspaces(oldTitle, title); err != nil {
+		log.WarningLog.Printf("could not rename %s in workspaces: %v", oldTitle, err)
+	}
+	if m.workspace != "" {
+		if workspaces, err := m.storage.LoadWorkspaces(); err == nil {
+			m.list.SetWorkspace(m.workspace, workspaces[m.workspace])
+		}
+	}
+	return m.storage.SaveInstances(m.list.GetInstances())
+}
+
+// checkInstanceLimit returns an error if creating another instance would exceed the configured limit.
+func (m *home) checkInstanceLimit() error {
+	limit := m.appConfig.MaxInstances
+	if limit > 0 && m.list.NumInstances() >= limit {
+		return fmt.Errorf("you can't create more than %d instances", limit)
+	}
+	return nil
+}
+
 // updatePreview updates the preview pane with the currently selected instance
 func (m *home) updatePreview() (tea.Model, tea.Cmd) {
 	selected := m.list.GetSelectedInstance()
+	if selected != nil {
+		if err := selected.RestoreDeferred(); err != nil {
+			return m.showErrorMessageForShortTime(err)
+		}
+	}
 
 	if err := m.tabbedWindow.UpdatePreview(selected); err != nil {
 		return m.showErrorMessageForShortTime(err)
@@ -523,11 +2087,69 @@ type previewTickMsg struct{}
 
 type tickUpdateMetadataMessage struct{}
 
-// tickUpdateMetadataCmd is the callback to update the metadata of the instances every 500ms. Note that we iterate
-// overall the instances and capture their output. It's a pretty expensive operation. Let's do it 2x a second only.
-var tickUpdateMetadataCmd = func() tea.Msg {
-	time.Sleep(500 * time.Millisecond)
-	return tickUpdateMetadataMessage{}
+// autoSaveTickMsg triggers saving the instances in the background.
+type autoSaveTickMsg struct{}
+
+// autoSaveDoneMsg is sent when an auto-save finished writing.
+type autoSaveDoneMsg struct {
+	err error
+}
+
+// instanceStartedMsg is sent when starting a new instance in the background finished.
+type instanceStartedMsg struct {
+	// instance is the instance in the list, and started is the copy which was started in its place.
+	instance *session.Instance
+	started  *session.Instance
+	err      error
+}
+
+// lowPowerMultiplier is how much slower the tick intervals are in low power mode.
+const lowPowerMultiplier = 4
+
+// tickInterval returns the configured interval in milliseconds as a duration, slowed down in low power mode.
+// Non-positive values fall back to defaultMs so we never spin.
+func (m *home) tickInterval(ms int, defaultMs int) time.Duration {
+	if ms <= 0 {
+		ms = defaultMs
+	}
+	interval := time.Duration(ms) * time.Millisecond
+	if m.lowPower {
+		interval *= lowPowerMultiplier
+	}
+	return interval
+}
+
+// tickPreviewCmd is the callback to update the preview pane, every 100ms by default.
+func (m *home) tickPreviewCmd() tea.Cmd {
+	interval := m.tickInterval(m.appConfig.PreviewIntervalMs, 100)
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return previewTickMsg{}
+	}
+}
+
+// tickUpdateMetadataCmd is the callback to update the metadata of the instances, every 500ms by default. Note that
+// we iterate overall the instances and capture their output. It's a pretty expensive operation. Let's do it 2x a
+// second only.
+func (m *home) tickUpdateMetadataCmd() tea.Cmd {
+	interval := m.tickInterval(m.appConfig.MetadataIntervalMs, 500)
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return tickUpdateMetadataMessage{}
+	}
+}
+
+// tickAutoSaveCmd is the callback to auto-save the instances, every 30 seconds by default. It returns nil if
+// auto-saving is disabled.
+func (m *home) tickAutoSaveCmd() tea.Cmd {
+	if m.appConfig.AutoSaveIntervalSec <= 0 {
+		return nil
+	}
+	interval := time.Duration(m.appConfig.AutoSaveIntervalSec) * time.Second
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return autoSaveTickMsg{}
+	}
 }
 
 // showErrorMessageForShortTime sets the error message. We return a callback. I assume bubbletea calls the
@@ -546,6 +2168,20 @@ func (m *home) showErrorMessageForShortTime(err error) (tea.Model, tea.Cmd) {
 	}
 }
 
+// showInfoMessageForShortTime is like showErrorMessageForShortTime, but for non-error messages such as
+// confirmations.
+func (m *home) showInfoMessageForShortTime(info string) (tea.Model, tea.Cmd) {
+	m.errBox.SetInfo(info)
+	return m, func() tea.Msg {
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(3 * time.Second):
+		}
+
+		return hideErrMsg{}
+	}
+}
+
 func (m *home) View() string {
 	listWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.list.String())
 	previewWithPadding := lipgloss.NewStyle().PaddingTop(1).Render(m.tabbedWindow.String())
@@ -555,15 +2191,32 @@ func (m *home) View() string {
 		lipgloss.Center,
 		listAndPreview,
 		m.menu.String(),
+		m.statusBar.String(),
 		m.errBox.String(),
 	)
 
-	if m.state == statePrompt {
+	if m.state == statePrompt || m.state == stateTextInput {
 		if m.textInputOverlay == nil {
 			log.ErrorLog.Printf("text input overlay is nil")
 		}
 		return overlay.PlaceOverlay(0, 0, m.textInputOverlay.Render(30, 120), mainView, true, true)
 	}
 
+	if m.state == stateConfirm {
+		return overlay.PlaceOverlay(0, 0, m.confirmationOverlay.Render(), mainView, true, true)
+	}
+
+	if m.state == stateSelect {
+		return overlay.PlaceOverlay(0, 0, m.selectionOverlay.Render(), mainView, true, true)
+	}
+
+	if m.state == stateLog {
+		return overlay.PlaceOverlay(0, 0, m.logOverlay.Render(), mainView, true, true)
+	}
+
+	if m.state == stateSummary {
+		return overlay.PlaceOverlay(0, 0, m.summaryOverlay.Render(), mainView, true, true)
+	}
+
 	return mainView
 }
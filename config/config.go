@@ -2,10 +2,17 @@ package config
 
 import (
 	"claude-squad/log"
+	"claude-squad/session/tmux"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 )
 
 // GetConfigDir returns the path to the application's configuration directory
@@ -17,20 +24,338 @@ func GetConfigDir() (string, error) {
 	return filepath.Join(homeDir, ".claude-squad"), nil
 }
 
+// The instance list takes between MinListWidthPercent and MaxListWidthPercent of the window width, so
+// neither the list nor the preview become unusable.
+const (
+	MinListWidthPercent = 15
+	MaxListWidthPercent = 60
+)
+
 // Config represents the application configuration
 type Config struct {
 	// DefaultProgram is the default program to run in new instances
 	DefaultProgram string `json:"default_program"`
 	// AutoYes
 	AutoYes bool `json:"auto_yes"`
+	// MaxInstances is the maximum number of instances that can exist at once. 0 means unlimited.
+	MaxInstances int `json:"max_instances"`
+	// DiffExportDir is the directory exported diffs are written to. Defaults to ~/claudesquad-diffs.
+	DiffExportDir string `json:"diff_export_dir"`
+	// CopyDiffToClipboard also copies exported diffs to the clipboard.
+	CopyDiffToClipboard bool `json:"copy_diff_to_clipboard"`
+	// TranscriptDir is the directory the full history of a session is saved to when it's paused or killed. Off
+	// when empty.
+	TranscriptDir string `json:"transcript_dir"`
+	// TranscriptKeepANSI keeps colors and other escape sequences in saved transcripts.
+	TranscriptKeepANSI bool `json:"transcript_keep_ansi"`
+	// Keybindings remaps keybindings. Keys are the canonical key names (ex. "kill") and values are the
+	// terminal keys to use, separated by commas (ex. "ctrl+d").
+	Keybindings map[string]string `json:"keybindings,omitempty"`
+	// PreviewIntervalMs is how often the preview pane is refreshed, in milliseconds.
+	PreviewIntervalMs int `json:"preview_interval_ms"`
+	// MetadataIntervalMs is how often instance statuses and diff stats are refreshed, in milliseconds.
+	MetadataIntervalMs int `json:"metadata_interval_ms"`
+	// MaxTitleLength is the maximum length of a session title.
+	MaxTitleLength int `json:"max_title_length"`
+	// AutoSaveIntervalSec is how often instances are saved while the app runs, in seconds, so their metadata
+	// survives a crash. 0 disables auto-saving.
+	AutoSaveIntervalSec int `json:"auto_save_interval_sec"`
+	// ConfirmKill asks for confirmation before killing an instance.
+	ConfirmKill bool `json:"confirm_kill"`
+	// AutoPauseIdleMinutes pauses instances which have been ready without any output for this many minutes.
+	// Their changes are only committed locally, never pushed. 0 disables auto-pausing.
+	AutoPauseIdleMinutes int `json:"auto_pause_idle_minutes"`
+	// LogLevel is the minimum level of messages written to the log file: error, warn, info or debug.
+	LogLevel string `json:"log_level"`
+	// LogFile is the path of the log file. Environment variables and "~" are expanded. Defaults to
+	// claudesquad.log in the temp directory.
+	LogFile string `json:"log_file"`
+	// LogMaxSizeMB is the size in megabytes the log file is rotated at. 0 disables rotation.
+	LogMaxSizeMB int `json:"log_max_size_mb"`
+	// LogMaxFiles is how many rotated log files are kept.
+	LogMaxFiles int `json:"log_max_files"`
+	// TmuxPrefix is prepended to the names of managed tmux sessions. Use a different prefix to stop multiple
+	// users or checkouts on one machine from colliding. Sessions created with another prefix can't be restored.
+	TmuxPrefix string `json:"tmux_prefix"`
+	// ListWidthPercent is the percentage of the window width taken by the instance list, between
+	// MinListWidthPercent and MaxListWidthPercent.
+	ListWidthPercent int `json:"list_width_percent"`
+	// OpenEditor is the command used to open a session's worktree, ex. "code -w". Defaults to $EDITOR.
+	OpenEditor string `json:"open_editor"`
+	// TrustScreenTimeoutMs is how long to wait for the "do you trust the files" screen when starting a session.
+	TrustScreenTimeoutMs int `json:"trust_screen_timeout_ms"`
+	// TrustScreenPollIntervalMs is how often to check for the trust screen, in milliseconds.
+	TrustScreenPollIntervalMs int `json:"trust_screen_poll_interval_ms"`
+	// AutoAcceptTrust accepts the "do you trust the files" screen when starting a session. Turn it off to answer
+	// it yourself when attaching.
+	AutoAcceptTrust bool `json:"auto_accept_trust"`
+	// TmuxConfigFile is a tmux config for the managed sessions, ex. one without bindings that get in the way of
+	// attaching. They then run on their own tmux server. A default config is written to it if it doesn't exist.
+	// Empty uses your usual tmux server and config.
+	TmuxConfigFile string `json:"tmux_config_file"`
+	// TmuxStartAttempts is how many times to try creating a tmux session before giving up.
+	TmuxStartAttempts int `json:"tmux_start_attempts"`
+	// DefaultPromptTemplate is sent to every new instance as soon as it starts. It's a text/template which can
+	// use {{.Title}} and {{.Path}}.
+	DefaultPromptTemplate string `json:"default_prompt_template"`
+	// CreatePROnSubmit asks for a title and body and opens a pull request after pushing a session's branch.
+	CreatePROnSubmit bool `json:"create_pr_on_submit"`
+	// SubmitDryRun shows the branch, commit message and changed files which would be pushed on submit, and only
+	// pushes after confirming.
+	SubmitDryRun bool `json:"submit_dry_run"`
+	// PreviewMaxChars limits how much scrollback the preview keeps when scrolling through history. The oldest
+	// lines are dropped first. 0 means no limit.
+	PreviewMaxChars int `json:"preview_max_chars"`
+	// PreviewHistoryLines is how many lines of scrollback above the visible pane are captured for the preview
+	// in follow mode, so they can be searched. 0 captures only the visible pane. At most 5000.
+	PreviewHistoryLines int `json:"preview_history_lines"`
+	// Notifications shows a desktop notification when a session is waiting for input and autoyes is off.
+	Notifications bool `json:"notifications"`
+	// SoundOnComplete plays a sound when a session finishes working and is ready for input, or fails to start or
+	// to compute its diff.
+	SoundOnComplete bool `json:"sound_on_complete"`
+	// SoundCommand is the command used to play the sound, ex. "afplay /System/Library/Sounds/Glass.aiff".
+	// Defaults to ringing the terminal bell.
+	SoundCommand string `json:"sound_command"`
+	// PreviewWrapMode is how the preview displays lines wider than the pane: wrap, truncate or off.
+	PreviewWrapMode string `json:"preview_wrap_mode"`
+	// SortOrder is the order sessions are listed in: created, title, status, recent or manual, which keeps the
+	// order they were last arranged in.
+	SortOrder string `json:"sort_order"`
+	// Profiles maps short names to programs, ex. "aider-gemma": "aider --model ollama_chat/gemma3:1b". They can
+	// be picked with --profile or when creating an instance.
+	Profiles map[string]string `json:"profiles,omitempty"`
+	// Templates maps names to presets for new sessions. They can be picked with --template or when creating an
+	// instance.
+	Templates map[string]Template `json:"templates,omitempty"`
+	// WorktreeBaseDir is the directory new worktrees are created in, ex. a faster disk. It must exist and be
+	// writable. Defaults to the worktrees directory in the config directory.
+	WorktreeBaseDir string `json:"worktree_base_dir"`
+	// LazyLoad restores the tmux sessions of stored instances when they're first selected instead of on startup,
+	// which starts faster with many sessions. Their status isn't updated until then.
+	LazyLoad bool `json:"lazy_load"`
+	// TerminalCommand opens a terminal emulator window, ex. "kitty", "wezterm start --" or "gnome-terminal --".
+	// When set, attaching opens the session in a new window running the tmux attach command, which is appended
+	// to it, and the TUI stays usable. Attaching is embedded when empty.
+	TerminalCommand string `json:"terminal_command"`
+	// ExtraPaneCommand is run in a second tmux pane next to the program in each new session, ex. "bash" for a
+	// side terminal in the worktree. Off when empty.
+	ExtraPaneCommand string `json:"extra_pane_command"`
+	// DiffFilters are presets of comma separated glob patterns which select the files shown in the diff, ex.
+	// "*.go,!*_test.go". Patterns starting with "!" exclude files. Cycle through them with the diff filter key.
+	DiffFilters []string `json:"diff_filters,omitempty"`
+	// AutoCommitOnPause commits a session's changes locally when it's paused, instead of pushing them.
+	AutoCommitOnPause bool `json:"auto_commit_on_pause"`
+	// MergeStrategy is how the merge key integrates a session's branch into the branch checked out in its
+	// repository: merge creates a merge commit, rebase rebases the branch onto it and fast-forwards.
+	MergeStrategy string `json:"merge_strategy"`
+	// CommitAuthorName and CommitAuthorEmail are the identity commits made by claude-squad are attributed to, ex.
+	// "claude-squad bot". Empty values use the git identity configured in the worktree.
+	CommitAuthorName  string `json:"commit_author_name"`
+	CommitAuthorEmail string `json:"commit_author_email"`
+	// Env are environment variables set in every new session, ex. "OPENAI_API_KEY": "$MY_OPENAI_KEY". Values can
+	// reference the host environment with $NAME or ${NAME}. They override variables of the same name from the host.
+	Env map[string]string `json:"env,omitempty"`
+	// PromptPatterns are regular expressions which detect that a program is waiting for the user to answer a
+	// prompt, for autoyes and notifications. Defaults to the prompts of claude and aider.
+	PromptPatterns []string `json:"prompt_patterns"`
+	// AutoYesDenyPatterns are regular expressions which stop autoyes from answering a prompt if the pane matches
+	// them, ex. "rm -rf" or "git push (-f|--force)". The prompt is left for the user and notifies like any other.
+	AutoYesDenyPatterns []string `json:"autoyes_deny_patterns,omitempty"`
+}
+
+// Template presets the program, repository, tags and first prompt of new sessions. Empty fields fall back to the
+// usual defaults.
+type Template struct {
+	// Program is the program to run, ex. "aider --model ollama_chat/gemma3:1b".
+	Program string `json:"program,omitempty"`
+	// Path is the repository to create the session in.
+	Path string `json:"path,omitempty"`
+	// Prompt is sent to the session once it starts.
+	Prompt string   `json:"prompt,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		DefaultProgram: "claude",
-		AutoYes:        false,
+		DefaultProgram:            "claude",
+		AutoYes:                   false,
+		MaxInstances:              10,
+		PreviewIntervalMs:         100,
+		MetadataIntervalMs:        500,
+		MaxTitleLength:            32,
+		AutoSaveIntervalSec:       30,
+		ConfirmKill:               true,
+		LogLevel:                  "info",
+		LogMaxSizeMB:              10,
+		LogMaxFiles:               3,
+		TmuxPrefix:                "claudesquad-",
+		ListWidthPercent:          30,
+		TrustScreenTimeoutMs:      5000,
+		TrustScreenPollIntervalMs: 200,
+		AutoAcceptTrust:           true,
+		TmuxStartAttempts:         3,
+		PreviewMaxChars:           50000,
+		PromptPatterns:            slices.Clone(tmux.DefaultPromptPatterns),
+		PreviewWrapMode:           "wrap",
+		SortOrder:                 "created",
+		MergeStrategy:             "merge",
+	}
+}
+
+// Validate returns an error describing every setting which is out of range or invalid. Settings are named by their
+// keys in the config file.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	atLeast := func(name string, value, minimum int) {
+		check(value >= minimum, "%s must be >= %d, got %d", name, minimum, value)
+	}
+
+	check(strings.TrimSpace(c.DefaultProgram) != "", "default_program must not be empty")
+	atLeast("max_instances", c.MaxInstances, 0)
+	atLeast("preview_interval_ms", c.PreviewIntervalMs, 0)
+	atLeast("metadata_interval_ms", c.MetadataIntervalMs, 0)
+	atLeast("max_title_length", c.MaxTitleLength, 0)
+	atLeast("auto_save_interval_sec", c.AutoSaveIntervalSec, 0)
+	atLeast("auto_pause_idle_minutes", c.AutoPauseIdleMinutes, 0)
+	atLeast("trust_screen_timeout_ms", c.TrustScreenTimeoutMs, 0)
+	atLeast("trust_screen_poll_interval_ms", c.TrustScreenPollIntervalMs, 0)
+	atLeast("tmux_start_attempts", c.TmuxStartAttempts, 0)
+	atLeast("preview_max_chars", c.PreviewMaxChars, 0)
+	atLeast("preview_history_lines", c.PreviewHistoryLines, 0)
+	atLeast("log_max_size_mb", c.LogMaxSizeMB, 0)
+	atLeast("log_max_files", c.LogMaxFiles, 0)
+	check(c.ListWidthPercent >= MinListWidthPercent && c.ListWidthPercent <= MaxListWidthPercent,
+		"list_width_percent must be between %d and %d, got %d", MinListWidthPercent, MaxListWidthPercent,
+		c.ListWidthPercent)
+
+	if _, err := log.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	check(c.PreviewWrapMode == "" || slices.Contains([]string{"wrap", "truncate", "off"}, strings.ToLower(c.PreviewWrapMode)),
+		"preview_wrap_mode must be wrap, truncate or off, got %q", c.PreviewWrapMode)
+	check(c.SortOrder == "" || slices.Contains([]string{"created", "title", "status", "recent", "manual"}, strings.ToLower(c.SortOrder)),
+		"sort_order must be created, title, status, recent or manual, got %q", c.SortOrder)
+	check(c.MergeStrategy == "" || slices.Contains([]string{"merge", "rebase"}, strings.ToLower(c.MergeStrategy)),
+		"merge_strategy must be merge or rebase, got %q", c.MergeStrategy)
+	check(!strings.ContainsAny(c.TmuxPrefix, ".: \t\n"), "tmux_prefix must not contain '.', ':' or whitespace, got %q", c.TmuxPrefix)
+
+	for _, pattern := range c.PromptPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("prompt_patterns: invalid pattern %q: %w", pattern, err))
+		}
+	}
+	for _, pattern := range c.AutoYesDenyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("autoyes_deny_patterns: invalid pattern %q: %w", pattern, err))
+		}
+	}
+	for _, name := range c.ProfileNames() {
+		check(strings.TrimSpace(c.Profiles[name]) != "", "profiles: %s has no program", name)
+	}
+	return errors.Join(errs...)
+}
+
+// unknownKeys returns the keys in the config file data which don't match any setting, in alphabetical order. They're
+// usually typos, or settings from another version of claude-squad.
+func unknownKeys(data []byte) ([]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+	var unknown []string
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// GetDiffExportDir returns the directory exported diffs are written to.
+func (c *Config) GetDiffExportDir() (string, error) {
+	if c.DiffExportDir != "" {
+		return c.DiffExportDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "claudesquad-diffs"), nil
+}
+
+// GetProfile returns the program of the profile with the given name.
+func (c *Config) GetProfile(name string) (string, error) {
+	program, ok := c.Profiles[name]
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q: available profiles are %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return program, nil
+}
+
+// ProfileNames returns the names of the profiles in alphabetical order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetTemplate returns the template with the given name.
+func (c *Config) GetTemplate(name string) (Template, error) {
+	template, ok := c.Templates[name]
+	if !ok {
+		return Template{}, fmt.Errorf("unknown template %q: available templates are %s", name,
+			strings.Join(c.TemplateNames(), ", "))
+	}
+	return template, nil
+}
+
+// TemplateNames returns the names of the templates in alphabetical order.
+func (c *Config) TemplateNames() []string {
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetEnv returns the environment variables for new sessions as NAME=value entries sorted by name, with
+// references to host environment variables expanded.
+func (c *Config) GetEnv() []string {
+	env := make([]string, 0, len(c.Env))
+	for name, value := range c.Env {
+		env = append(env, name+"="+os.ExpandEnv(value))
 	}
+	sort.Strings(env)
+	return env
+}
+
+// GetEditor returns the command used to open worktrees in an editor.
+func (c *Config) GetEditor() (string, error) {
+	if c.OpenEditor != "" {
+		return c.OpenEditor, nil
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor, nil
+	}
+	return "", fmt.Errorf("no editor configured: set open_editor in the config or $EDITOR")
 }
 
 // LoadConfig loads the configuration from disk
@@ -54,12 +379,23 @@ func LoadConfig() (*Config, error) {
 		return DefaultConfig(), fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	// Unmarshal on top of the defaults so fields missing from older config files keep their default values.
+	config := DefaultConfig()
+	if err := json.Unmarshal(data, config); err != nil {
 		return DefaultConfig(), fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if err := config.Validate(); err != nil {
+		return DefaultConfig(), fmt.Errorf("invalid config file %s:\n%w", configPath, err)
+	}
+	// Unknown keys are only warned about, so config files written by newer versions still load. The warning is
+	// printed too, since a misspelled key is easy to miss in the log. The TUI draws on the alternate screen, so the
+	// warning is still there after quitting it.
+	if unknown, err := unknownKeys(data); err == nil && len(unknown) > 0 {
+		log.WarningLog.Printf("ignoring unknown keys in %s: %s", configPath, strings.Join(unknown, ", "))
+		fmt.Fprintf(os.Stderr, "warning: ignoring unknown keys in %s: %s\n", configPath, strings.Join(unknown, ", "))
+	}
 
-	return &config, nil
+	return config, nil
 }
 
 // SaveConfig saves the configuration to disk
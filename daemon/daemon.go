@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -16,9 +17,10 @@ import (
 
 var daemonPollInterval = 1 * time.Second
 
-// RunDaemon runs the daemon process which iterates over all sessions and runs AutoYes mode on them.
-// It's expected that the main process kills the daemon when the main process starts.
-func RunDaemon() error {
+// RunDaemon runs the daemon process which iterates over all sessions with AutoYes on and answers their prompts.
+// If autoYes is set, the prompts of every session are answered. It's expected that the main process kills the
+// daemon when the main process starts.
+func RunDaemon(autoYes bool) error {
 	log.InfoLog.Printf("starting daemon")
 	storage, err := session.NewStorage()
 	if err != nil {
@@ -29,10 +31,6 @@ func RunDaemon() error {
 	if err != nil {
 		return fmt.Errorf("failed to load instacnes: %w", err)
 	}
-	for _, instance := range instances {
-		// Assume AutoYes is true if the daemon is running.
-		instance.AutoYes = true
-	}
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -42,9 +40,14 @@ func RunDaemon() error {
 		ticker := time.NewTimer(daemonPollInterval)
 		for {
 			for _, instance := range instances {
-				// We only store started instances, but check anyway.
-				if instance.Started() && !instance.Paused() {
+				// We only store started instances, but check anyway. Only instances with autoyes on are answered,
+				// which is every instance if autoyes was on for the whole app.
+				if instance.Started() && !instance.Paused() && (autoYes || instance.AutoYes) {
 					if _, hasPrompt := instance.HasUpdated(); hasPrompt {
+						if pattern, denied := instance.AutoYesDenied(); denied {
+							log.InfoLog.Printf("not answering prompt of %s, it matches deny pattern %q", instance.Title, pattern)
+							continue
+						}
 						instance.TapEnter()
 						if err := instance.UpdateDiffStats(); err != nil {
 							log.WarningLog.Printf("could not update diff stats for %s: %v", instance.Title, err)
@@ -83,15 +86,20 @@ func RunDaemon() error {
 	return nil
 }
 
-// LaunchDaemon launches the daemon process.
-func LaunchDaemon() error {
+// LaunchDaemon launches the daemon process. If autoYes is set, it answers the prompts of every session rather than
+// just the ones with autoyes on.
+func LaunchDaemon(autoYes bool) error {
 	// Find the claude squad binary.
 	execPath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("failed to get executable path: %w", err)
 	}
 
-	cmd := exec.Command(execPath, "--daemon")
+	args := []string{"--daemon"}
+	if autoYes {
+		args = append(args, "--autoyes")
+	}
+	cmd := exec.Command(execPath, args...)
 
 	// Detach the process from the parent
 	cmd.Stdin = nil
@@ -108,13 +116,16 @@ func LaunchDaemon() error {
 	log.InfoLog.Printf("started daemon child process with PID: %d", cmd.Process.Pid)
 
 	// Save PID to a file for later management
-	pidDir, err := config.GetConfigDir()
+	pidFile, err := getPidFilePath()
 	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
+		return err
 	}
-
-	pidFile := filepath.Join(pidDir, "daemon.pid")
-	if err := os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", cmd.Process.Pid)), 0644); err != nil {
+	// The mode is recorded after the PID so GetStatus knows which sessions the daemon answers.
+	content := fmt.Sprintf("%d\n", cmd.Process.Pid)
+	if autoYes {
+		content += autoYesMode + "\n"
+	}
+	if err := os.WriteFile(pidFile, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write PID file: %w", err)
 	}
 
@@ -124,24 +135,17 @@ func LaunchDaemon() error {
 
 // StopDaemon attempts to stop a running daemon process if it exists.
 func StopDaemon() error {
-	pidDir, err := config.GetConfigDir()
+	pidFile, err := getPidFilePath()
 	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
+		return err
 	}
-
-	pidFile := filepath.Join(pidDir, "daemon.pid")
-	data, err := os.ReadFile(pidFile)
+	pid, _, err := readPidFile(pidFile)
 	if err != nil {
 		if os.IsNotExist(err) {
 			log.InfoLog.Print("no daemon is running (PID file not found)")
 			return nil
 		}
-		return fmt.Errorf("failed to read PID file: %w", err)
-	}
-
-	var pid int
-	if _, err := fmt.Sscanf(string(data), "%d", &pid); err != nil {
-		return fmt.Errorf("invalid PID file format: %w", err)
+		return err
 	}
 
 	proc, err := os.FindProcess(pid)
@@ -161,3 +165,87 @@ func StopDaemon() error {
 	log.InfoLog.Printf("Daemon process (PID: %d) stopped successfully", pid)
 	return nil
 }
+
+// Status describes the state of the daemon process.
+type Status struct {
+	// Running is true if the daemon process is alive.
+	Running bool
+	// PID is the process ID from the PID file. 0 if there is no PID file.
+	PID int
+	// Uptime is how long the daemon has been running, based on when the PID file was written.
+	Uptime time.Duration
+	// AutoYes is true if the daemon answers the prompts of every session, rather than just the ones with autoyes on.
+	AutoYes bool
+	// ManagedSessions is the number of stored sessions the daemon runs autoyes mode on.
+	ManagedSessions int
+}
+
+// autoYesMode follows the PID in the PID file if the daemon answers the prompts of every session.
+const autoYesMode = "autoyes"
+
+// readPidFile returns the PID in the PID file and whether the daemon answers the prompts of every session.
+func readPidFile(path string) (int, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, err
+		}
+		return 0, false, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	var pid int
+	if _, err := fmt.Sscanf(string(data), "%d", &pid); err != nil {
+		return 0, false, fmt.Errorf("invalid PID file format: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	return pid, len(fields) > 1 && fields[1] == autoYesMode, nil
+}
+
+// GetStatus reports whether the daemon is running and what it's managing.
+func GetStatus() (*Status, error) {
+	status := &Status{}
+
+	pidFile, err := getPidFilePath()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to stat PID file: %w", err)
+	}
+	if status.PID, status.AutoYes, err = readPidFile(pidFile); err != nil {
+		return nil, err
+	}
+	status.Running = isProcessAlive(status.PID)
+	if !status.Running {
+		return status, nil
+	}
+	status.Uptime = time.Since(info.ModTime())
+
+	storage, err := session.NewStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	instances, err := storage.LoadInstanceData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+	// The daemon skips paused instances, and only answers the ones with autoyes on unless it answers every one.
+	for _, instance := range instances {
+		if instance.Status != session.Paused && (status.AutoYes || instance.AutoYes) {
+			status.ManagedSessions++
+		}
+	}
+	return status, nil
+}
+
+// getPidFilePath returns the path to the file storing the PID of the daemon.
+func getPidFilePath() (string, error) {
+	pidDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(pidDir, "daemon.pid"), nil
+}
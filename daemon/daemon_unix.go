@@ -3,6 +3,7 @@
 package daemon
 
 import (
+	"os"
 	"syscall"
 )
 
@@ -12,3 +13,13 @@ func getSysProcAttr() *syscall.SysProcAttr {
 		Setsid: true, // Create a new session
 	}
 }
+
+// isProcessAlive returns true if a process with the pid exists
+func isProcessAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks for existence without sending a signal.
+	return proc.Signal(syscall.Signal(0)) == nil
+}
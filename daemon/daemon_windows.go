@@ -4,6 +4,7 @@ package daemon
 
 import (
 	"golang.org/x/sys/windows"
+	"os"
 	"syscall"
 )
 
@@ -13,3 +14,14 @@ func getSysProcAttr() *syscall.SysProcAttr {
 		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
 	}
 }
+
+// isProcessAlive returns true if a process with the pid exists
+func isProcessAlive(pid int) bool {
+	// On Windows, FindProcess opens a handle to the process and fails if it doesn't exist.
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = proc.Release()
+	return true
+}
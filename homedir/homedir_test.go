@@ -0,0 +1,32 @@
+package homedir
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "~", want: home},
+		{path: "~/.claude-squad/tmux.conf", want: filepath.Join(home, ".claude-squad", "tmux.conf")},
+		{path: "~other/tmux.conf", want: "~other/tmux.conf"},
+		{path: "/tmp/~/tmux.conf", want: "/tmp/~/tmux.conf"},
+		{path: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Expand(tt.path)
+			if err != nil {
+				t.Fatalf("Expand(%q) returned error: %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -23,13 +23,57 @@ const (
 	KeyCheckout
 	KeyResume
 	KeyPrompt // New key for entering a prompt
+	KeyRename
+	KeyAttachReadOnly
+	KeyFollow
+	KeyExportDiff
+	KeyNewInPath
+	KeyLowPower
+	KeyNewWithProgram
+	KeyDuplicate
+	KeyBroadcast
+	KeyShrinkList
+	KeyGrowList
+	KeyKillAllAndQuit
+	KeySearch
+	KeyCopyPath
+	KeyOpenEditor
+	KeyTags
+	KeyRestart
+	KeyPromptHistory
+	KeyPin
+	KeySort
+	KeyLog
+	KeyDiffFilter
+	KeyPrune
+	KeyChangedFiles
+	KeyDescription
+	KeyNextAttention
+	KeyMoveUp
+	KeyMoveDown
+	KeyNewFromTemplate
+	KeyMarkSeen
+	KeySendKey
+	KeySummary
+	KeyCopyAttachCommand
+	KeyKillKeepWorktree
+	KeyHidePaused
+	KeyAutoYes
+	KeyMerge
+	KeyWorkspace
+	KeySaveWorkspace
+	KeyTop
+	KeyBottom
+	KeyJumpPercent
+	KeyPreviewTab
+	KeyDiffTab
 
 	// Diff keybindings
 	KeyShiftUp
 	KeyShiftDown
 )
 
-// GlobalKeyStringsMap is a global, immutable map string to keybinding.
+// GlobalKeyStringsMap is a global map string to keybinding. It's only modified at startup by ApplyOverrides.
 var GlobalKeyStringsMap = map[string]KeyName{
 	"up":         KeyUp,
 	"k":          KeyUp,
@@ -47,9 +91,53 @@ var GlobalKeyStringsMap = map[string]KeyName{
 	"c":          KeyCheckout,
 	"r":          KeyResume,
 	"s":          KeySubmit,
+	"R":          KeyRename,
+	"v":          KeyAttachReadOnly,
+	"f":          KeyFollow,
+	"x":          KeyExportDiff,
+	"P":          KeyNewInPath,
+	"L":          KeyLowPower,
+	"a":          KeyNewWithProgram,
+	"C":          KeyDuplicate,
+	"B":          KeyBroadcast,
+	"<":          KeyShrinkList,
+	">":          KeyGrowList,
+	"Q":          KeyKillAllAndQuit,
+	"/":          KeySearch,
+	"w":          KeyCopyPath,
+	"e":          KeyOpenEditor,
+	"t":          KeyTags,
+	"ctrl+r":     KeyRestart,
+	"h":          KeyPromptHistory,
+	"p":          KeyPin,
+	"S":          KeySort,
+	"ctrl+l":     KeyLog,
+	"F":          KeyDiffFilter,
+	"X":          KeyPrune,
+	"D":          KeyChangedFiles,
+	"I":          KeyDescription,
+	"J":          KeyNextAttention,
+	"ctrl+up":    KeyMoveUp,
+	"ctrl+down":  KeyMoveDown,
+	"T":          KeyNewFromTemplate,
+	"M":          KeyMarkSeen,
+	"K":          KeySendKey,
+	"=":          KeySummary,
+	"W":          KeyCopyAttachCommand,
+	"ctrl+x":     KeyKillKeepWorktree,
+	"H":          KeyHidePaused,
+	"Y":          KeyAutoYes,
+	"m":          KeyMerge,
+	"O":          KeyWorkspace,
+	"ctrl+w":     KeySaveWorkspace,
+	"g":          KeyTop,
+	"G":          KeyBottom,
+	"%":          KeyJumpPercent,
+	"alt+1":      KeyPreviewTab,
+	"alt+2":      KeyDiffTab,
 }
 
-// GlobalkeyBindings is a global, immutable map of KeyName tot keybinding.
+// GlobalkeyBindings is a global map of KeyName tot keybinding. It's only modified at startup by ApplyOverrides.
 var GlobalkeyBindings = map[KeyName]key.Binding{
 	KeyUp: key.NewBinding(
 		key.WithKeys("up", "k"),
@@ -103,6 +191,182 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("r"),
 		key.WithHelp("r", "resume"),
 	),
+	KeyRename: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "rename"),
+	),
+	KeyAttachReadOnly: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "view"),
+	),
+	KeyFollow: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "follow/scroll"),
+	),
+	KeyExportDiff: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "export diff"),
+	),
+	KeyNewInPath: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "new in path"),
+	),
+	KeyLowPower: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "low power"),
+	),
+	KeyNewWithProgram: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "new with program"),
+	),
+	KeyDuplicate: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "duplicate"),
+	),
+	KeyBroadcast: key.NewBinding(
+		key.WithKeys("B"),
+		key.WithHelp("B", "prompt all"),
+	),
+	KeyShrinkList: key.NewBinding(
+		key.WithKeys("<"),
+		key.WithHelp("<", "shrink list"),
+	),
+	KeyGrowList: key.NewBinding(
+		key.WithKeys(">"),
+		key.WithHelp(">", "grow list"),
+	),
+	KeyKillAllAndQuit: key.NewBinding(
+		key.WithKeys("Q"),
+		key.WithHelp("Q", "kill all and quit"),
+	),
+	KeySearch: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search"),
+	),
+	KeyCopyPath: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "copy path"),
+	),
+	KeyOpenEditor: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "open in editor"),
+	),
+	KeyTags: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "tags"),
+	),
+	KeyRestart: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "restart"),
+	),
+	KeyPromptHistory: key.NewBinding(
+		key.WithKeys("h"),
+		key.WithHelp("h", "prompt history"),
+	),
+	KeyPin: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pin"),
+	),
+	KeySort: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "sort"),
+	),
+	KeyLog: key.NewBinding(
+		key.WithKeys("ctrl+l"),
+		key.WithHelp("ctrl+l", "log"),
+	),
+	KeyDiffFilter: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "diff filter"),
+	),
+	KeyPrune: key.NewBinding(
+		key.WithKeys("X"),
+		key.WithHelp("X", "prune"),
+	),
+	KeyChangedFiles: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "changed files"),
+	),
+	KeyDescription: key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "description"),
+	),
+	KeyNextAttention: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "next waiting"),
+	),
+	KeyMoveUp: key.NewBinding(
+		key.WithKeys("ctrl+up"),
+		key.WithHelp("ctrl+↑", "move up"),
+	),
+	KeyMoveDown: key.NewBinding(
+		key.WithKeys("ctrl+down"),
+		key.WithHelp("ctrl+↓", "move down"),
+	),
+	KeyNewFromTemplate: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "new from template"),
+	),
+	KeyMarkSeen: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "mark seen"),
+	),
+	KeySendKey: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "send key"),
+	),
+	KeySummary: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "summary"),
+	),
+	KeyCopyAttachCommand: key.NewBinding(
+		key.WithKeys("W"),
+		key.WithHelp("W", "copy attach command"),
+	),
+	KeyKillKeepWorktree: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "kill, keep worktree"),
+	),
+	KeyHidePaused: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "hide paused"),
+	),
+	KeyAutoYes: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "autoyes"),
+	),
+	KeyMerge: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "merge"),
+	),
+	KeyWorkspace: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "workspace"),
+	),
+	KeySaveWorkspace: key.NewBinding(
+		key.WithKeys("ctrl+w"),
+		key.WithHelp("ctrl+w", "save workspace"),
+	),
+	KeyTop: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "top"),
+	),
+	KeyBottom: key.NewBinding(
+		key.WithKeys("G"),
+		key.WithHelp("G", "bottom"),
+	),
+	KeyJumpPercent: key.NewBinding(
+		key.WithKeys("%"),
+		key.WithHelp("%", "jump to %"),
+	),
+	KeyPreviewTab: key.NewBinding(
+		key.WithKeys("alt+1"),
+		key.WithHelp("alt+1", "preview tab"),
+	),
+	KeyDiffTab: key.NewBinding(
+		key.WithKeys("alt+2"),
+		key.WithHelp("alt+2", "diff tab"),
+	),
 
 	// -- Special keybindings --
 
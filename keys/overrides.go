@@ -0,0 +1,140 @@
+package keys
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// ConfigKeyNames maps the canonical names used in the config file to the keybindings they refer to.
+// Special keybindings like KeySubmitName are not remappable.
+var ConfigKeyNames = map[string]KeyName{
+	"up":                KeyUp,
+	"down":              KeyDown,
+	"scroll_up":         KeyShiftUp,
+	"scroll_down":       KeyShiftDown,
+	"enter":             KeyEnter,
+	"new":               KeyNew,
+	"prompt":            KeyPrompt,
+	"kill":              KeyKill,
+	"quit":              KeyQuit,
+	"tab":               KeyTab,
+	"checkout":          KeyCheckout,
+	"resume":            KeyResume,
+	"submit":            KeySubmit,
+	"rename":            KeyRename,
+	"attach_read_only":  KeyAttachReadOnly,
+	"follow":            KeyFollow,
+	"export_diff":       KeyExportDiff,
+	"new_in_path":       KeyNewInPath,
+	"low_power":         KeyLowPower,
+	"new_with_program":  KeyNewWithProgram,
+	"duplicate":         KeyDuplicate,
+	"broadcast":         KeyBroadcast,
+	"shrink_list":       KeyShrinkList,
+	"grow_list":         KeyGrowList,
+	"kill_all_quit":     KeyKillAllAndQuit,
+	"search":            KeySearch,
+	"copy_path":         KeyCopyPath,
+	"open_editor":       KeyOpenEditor,
+	"tags":              KeyTags,
+	"restart":           KeyRestart,
+	"prompt_history":    KeyPromptHistory,
+	"pin":               KeyPin,
+	"sort":              KeySort,
+	"log":               KeyLog,
+	"diff_filter":       KeyDiffFilter,
+	"prune":             KeyPrune,
+	"changed_files":     KeyChangedFiles,
+	"description":       KeyDescription,
+	"next_attention":    KeyNextAttention,
+	"move_up":           KeyMoveUp,
+	"move_down":         KeyMoveDown,
+	"new_from_template": KeyNewFromTemplate,
+	"mark_seen":         KeyMarkSeen,
+	"send_key":          KeySendKey,
+	"summary":           KeySummary,
+	"attach_command":    KeyCopyAttachCommand,
+	"kill_keep_tree":    KeyKillKeepWorktree,
+	"hide_paused":       KeyHidePaused,
+	"auto_yes":          KeyAutoYes,
+	"merge":             KeyMerge,
+	"workspace":         KeyWorkspace,
+	"save_workspace":    KeySaveWorkspace,
+	"top":               KeyTop,
+	"bottom":            KeyBottom,
+	"jump_percent":      KeyJumpPercent,
+	"preview_tab":       KeyPreviewTab,
+	"diff_tab":          KeyDiffTab,
+}
+
+// ApplyOverrides remaps keybindings using a map of canonical key names (see ConfigKeyNames) to terminal
+// key strings. Multiple keys can be given for one binding by separating them with commas (ex. "up,k").
+// A key which is taken from another binding is removed from that binding. Invalid entries are skipped
+// and returned as errors so the defaults stay in place.
+func ApplyOverrides(overrides map[string]string) []error {
+	var errs []error
+
+	// Sort the names so conflicts are resolved deterministically.
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		keyName, ok := ConfigKeyNames[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown keybinding name %q", name))
+			continue
+		}
+
+		var keyStrings []string
+		for _, k := range strings.Split(overrides[name], ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keyStrings = append(keyStrings, k)
+			}
+		}
+		if len(keyStrings) == 0 {
+			errs = append(errs, fmt.Errorf("no keys given for keybinding %q", name))
+			continue
+		}
+
+		// Unbind the old keys for this binding.
+		for k, existing := range GlobalKeyStringsMap {
+			if existing == keyName {
+				delete(GlobalKeyStringsMap, k)
+			}
+		}
+		for _, k := range keyStrings {
+			if existing, ok := GlobalKeyStringsMap[k]; ok && existing != keyName {
+				errs = append(errs, fmt.Errorf("key %q for %q replaces an existing keybinding", k, name))
+				removeKey(existing, k)
+			}
+			GlobalKeyStringsMap[k] = keyName
+		}
+
+		GlobalkeyBindings[keyName] = key.NewBinding(
+			key.WithKeys(keyStrings...),
+			key.WithHelp(strings.Join(keyStrings, "/"), GlobalkeyBindings[keyName].Help().Desc),
+		)
+	}
+	return errs
+}
+
+// removeKey removes a single key from the binding for name.
+func removeKey(name KeyName, k string) {
+	binding := GlobalkeyBindings[name]
+	var remaining []string
+	for _, existing := range binding.Keys() {
+		if existing != k {
+			remaining = append(remaining, existing)
+		}
+	}
+	GlobalkeyBindings[name] = key.NewBinding(
+		key.WithKeys(remaining...),
+		key.WithHelp(strings.Join(remaining, "/"), binding.Help().Desc),
+	)
+}
@@ -0,0 +1,85 @@
+package keys
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// restoreGlobals snapshots the global keymaps and restores them when the test finishes.
+func restoreGlobals(t *testing.T) {
+	keyStrings := make(map[string]KeyName, len(GlobalKeyStringsMap))
+	for k, v := range GlobalKeyStringsMap {
+		keyStrings[k] = v
+	}
+	bindings := make(map[KeyName]key.Binding, len(GlobalkeyBindings))
+	for k, v := range GlobalkeyBindings {
+		bindings[k] = v
+	}
+	t.Cleanup(func() {
+		GlobalKeyStringsMap = keyStrings
+		GlobalkeyBindings = bindings
+	})
+}
+
+func TestApplyOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErrs  int
+		bound     map[string]KeyName
+		unbound   []string
+	}{
+		{
+			name:      "remap single key",
+			overrides: map[string]string{"kill": "ctrl+d"},
+			bound:     map[string]KeyName{"ctrl+d": KeyKill},
+			unbound:   []string{"d"},
+		},
+		{
+			name:      "remap multiple keys",
+			overrides: map[string]string{"up": "up, i"},
+			bound:     map[string]KeyName{"up": KeyUp, "i": KeyUp},
+			unbound:   []string{"k"},
+		},
+		{
+			name:      "unknown name is skipped",
+			overrides: map[string]string{"nope": "z"},
+			wantErrs:  1,
+			unbound:   []string{"z"},
+		},
+		{
+			name:      "empty keys are skipped",
+			overrides: map[string]string{"kill": " , "},
+			wantErrs:  1,
+			bound:     map[string]KeyName{"d": KeyKill},
+		},
+		{
+			name:      "taking a key from another binding",
+			overrides: map[string]string{"kill": "n"},
+			wantErrs:  1,
+			bound:     map[string]KeyName{"n": KeyKill},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreGlobals(t)
+
+			errs := ApplyOverrides(tt.overrides)
+			if len(errs) != tt.wantErrs {
+				t.Errorf("ApplyOverrides(%v) returned %d errors, want %d: %v", tt.overrides, len(errs), tt.wantErrs, errs)
+			}
+			for k, want := range tt.bound {
+				if got, ok := GlobalKeyStringsMap[k]; !ok || got != want {
+					t.Errorf("key %q is bound to %v (ok=%v), want %v", k, got, ok, want)
+				}
+			}
+			for _, k := range tt.unbound {
+				if got, ok := GlobalKeyStringsMap[k]; ok {
+					t.Errorf("key %q should be unbound, but is bound to %v", k, got)
+				}
+			}
+		})
+	}
+}
@@ -1,28 +1,103 @@
 package log
 
 import (
+	"claude-squad/homedir"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var (
+	DebugLog   *log.Logger
 	WarningLog *log.Logger
 	InfoLog    *log.Logger
 	ErrorLog   *log.Logger
 )
 
+// Level is the minimum severity of messages which are written to the log file.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+// ParseLevel parses a level name (error, warn, info or debug). The empty string parses as LevelInfo.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q (expected error, warn, info or debug)", s)
+	}
+}
+
 var logFileName = filepath.Join(os.TempDir(), "claudesquad.log")
 
-var globalLogFile *os.File
+var globalLogFile *rotatingFile
+
+// maxSize and maxBackups are the rotation limits of the log file. Change them with SetRotation.
+var (
+	maxSize    int64 = defaultMaxSize
+	maxBackups       = defaultMaxBackups
+)
+
+// currentLevel is the level set by SetLevel, kept so SetFile can apply it to the new file.
+var currentLevel = LevelInfo
+
+// FilePath returns the path of the log file.
+func FilePath() string {
+	return logFileName
+}
+
+// Tail returns up to the last n lines of the log file.
+func Tail(n int) (string, error) {
+	f, err := os.Open(logFileName)
+	if err != nil {
+		return "", fmt.Errorf("could not open log file: %w", err)
+	}
+	defer f.Close()
+
+	// Only read the end of the file, which is plenty for n lines. Lines from before the last rotation aren't shown.
+	const maxTailBytes = 64 * 1024
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("could not stat log file: %w", err)
+	}
+	offset := max(info.Size()-maxTailBytes, 0)
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return "", fmt.Errorf("could not read log file: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if offset > 0 && len(lines) > 1 {
+		// The first line is probably partial.
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
 
 // Initialize should be called once at the beginning of the program to set up logging.
 // defer Close() after calling this function. It sets the go log output to the file in
-// the os temp directory.
+// the os temp directory. Everything except debug messages is logged until SetLevel is called.
 
 func Initialize(daemon bool) {
-	f, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	f, err := openRotatingFile(logFileName, maxSize, maxBackups)
 	if err != nil {
 		panic(fmt.Sprintf("could not open log file: %s", err))
 	}
@@ -34,11 +109,64 @@ func Initialize(daemon bool) {
 	if daemon {
 		fmtS = "%s [DAEMON]"
 	}
+	DebugLog = log.New(f, fmt.Sprintf(fmtS, "DEBUG:"), log.Ldate|log.Ltime|log.Lshortfile)
 	InfoLog = log.New(f, fmt.Sprintf(fmtS, "INFO:"), log.Ldate|log.Ltime|log.Lshortfile)
 	WarningLog = log.New(f, fmt.Sprintf(fmtS, "WARNING:"), log.Ldate|log.Ltime|log.Lshortfile)
 	ErrorLog = log.New(f, fmt.Sprintf(fmtS, "ERROR:"), log.Ldate|log.Ltime|log.Lshortfile)
 
 	globalLogFile = f
+	SetLevel(LevelInfo)
+}
+
+// SetFile moves logging to the file at path, creating its parent directories if needed. Environment variables and
+// a leading "~" are expanded. An empty path keeps logging to the default file in the temp directory. Must be
+// called after Initialize.
+func SetFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	path, err := homedir.Expand(os.ExpandEnv(path))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create log file directory: %w", err)
+	}
+	f, err := openRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		return fmt.Errorf("log file %s is not writable: %w", path, err)
+	}
+
+	_ = globalLogFile.Close()
+	globalLogFile = f
+	logFileName = path
+	SetLevel(currentLevel)
+	return nil
+}
+
+// SetRotation sets the size in bytes the log file is rotated at, and how many rotated files are kept next to it as
+// .1, .2 and so on. A non-positive size disables rotation.
+func SetRotation(size int64, backups int) {
+	maxSize = size
+	maxBackups = max(backups, 0)
+	if globalLogFile != nil {
+		globalLogFile.setLimits(maxSize, maxBackups)
+	}
+}
+
+// SetLevel discards messages from the loggers below level. Must be called after Initialize.
+func SetLevel(level Level) {
+	currentLevel = level
+	output := func(l Level) io.Writer {
+		if l > level {
+			return io.Discard
+		}
+		return globalLogFile
+	}
+	DebugLog.SetOutput(output(LevelDebug))
+	InfoLog.SetOutput(output(LevelInfo))
+	WarningLog.SetOutput(output(LevelWarn))
+	ErrorLog.SetOutput(output(LevelError))
 }
 
 func Close() {
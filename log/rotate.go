@@ -0,0 +1,128 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// defaultMaxSize is the size the log file is rotated at unless SetRotation changes it.
+	defaultMaxSize = 10 * 1024 * 1024
+	// defaultMaxBackups is the number of rotated log files kept unless SetRotation changes it.
+	defaultMaxBackups = 3
+)
+
+// rotatingFile is a log file which is renamed to path.1 once it grows past maxSize, shifting older files to
+// path.2 and so on, and started again empty. Files beyond maxBackups are deleted. Several processes can share the
+// file, ex. the app and the daemon: each one follows the size of the file on disk and reopens it when another one
+// rotated it. Two processes rotating at the same moment can shift a backup one place too many.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+// openRotatingFile opens the log file at path for appending, creating it if needed.
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// Write writes p to the file, rotating it first if p would take it past maxSize. A single write is never split
+// across files. If rotating fails, logging carries on in the current file.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.follow()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "could not rotate log file: %v\n", err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// follow catches up with other processes writing to the file. Their writes count towards its size, and if one of
+// them rotated it, there's a new file at path which is reopened.
+func (r *rotatingFile) follow() {
+	info, err := os.Stat(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		if current, err := r.file.Stat(); err == nil && os.SameFile(info, current) {
+			r.size = info.Size()
+			return
+		}
+	}
+	_ = r.file.Close()
+	if err := r.open(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not reopen log file: %v\n", err)
+	}
+}
+
+// rotate shifts the rotated files along, moves the current file to path.1 and opens a new one.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	var renameErr error
+	if r.maxBackups > 0 {
+		// The oldest file falls off the end.
+		_ = os.Remove(r.backupPath(r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(r.backupPath(i), r.backupPath(i+1))
+		}
+		renameErr = os.Rename(r.path, r.backupPath(1))
+	} else {
+		renameErr = os.Remove(r.path)
+	}
+	// Reopen even if the rename failed, so there's always a file to write to.
+	if err := r.open(); err != nil {
+		return err
+	}
+	return renameErr
+}
+
+func (r *rotatingFile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
+
+// setLimits changes when the file is rotated and how many rotated files are kept.
+func (r *rotatingFile) setLimits(maxSize int64, maxBackups int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSize = maxSize
+	r.maxBackups = maxBackups
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
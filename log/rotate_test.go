@@ -0,0 +1,141 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFile returns the content of path, or "missing" if it doesn't exist.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "missing"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// openTestFile opens a rotating log file in a temporary directory and closes it when the test ends.
+func openTestFile(t *testing.T, maxSize int64, maxBackups int) (*rotatingFile, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	r, err := openRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		t.Fatalf("openRotatingFile() returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r, path
+}
+
+func write(t *testing.T, r *rotatingFile, s string) {
+	t.Helper()
+	if n, err := r.Write([]byte(s)); err != nil || n != len(s) {
+		t.Fatalf("Write(%q) = %d, %v", s, n, err)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxSize    int64
+		maxBackups int
+		writes     []string
+		// want is the content of the log file followed by its backups, .1 first.
+		want []string
+	}{
+		{
+			name:       "below the limit",
+			maxSize:    10,
+			maxBackups: 2,
+			writes:     []string{"aaaa\n", "bbbb\n"},
+			want:       []string{"aaaa\nbbbb\n", "missing"},
+		},
+		{
+			name:       "rotates past the limit",
+			maxSize:    10,
+			maxBackups: 2,
+			writes:     []string{"aaaa\n", "bbbb\n", "cccc\n"},
+			want:       []string{"cccc\n", "aaaa\nbbbb\n", "missing"},
+		},
+		{
+			name:       "shifts the backups",
+			maxSize:    5,
+			maxBackups: 2,
+			writes:     []string{"aaaa\n", "bbbb\n", "cccc\n"},
+			want:       []string{"cccc\n", "bbbb\n", "aaaa\n"},
+		},
+		{
+			name:       "deletes backups beyond the limit",
+			maxSize:    5,
+			maxBackups: 2,
+			writes:     []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"},
+			want:       []string{"dddd\n", "cccc\n", "bbbb\n", "missing"},
+		},
+		{
+			name:       "no backups",
+			maxSize:    5,
+			maxBackups: 0,
+			writes:     []string{"aaaa\n", "bbbb\n"},
+			want:       []string{"bbbb\n", "missing"},
+		},
+		{
+			name:       "never splits a write",
+			maxSize:    5,
+			maxBackups: 1,
+			writes:     []string{"aa\n", "a write longer than the limit\n", "bb\n"},
+			want:       []string{"bb\n", "a write longer than the limit\n", "missing"},
+		},
+		{
+			name:       "rotation disabled",
+			maxSize:    0,
+			maxBackups: 1,
+			writes:     []string{"aaaa\n", "bbbb\n"},
+			want:       []string{"aaaa\nbbbb\n", "missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, path := openTestFile(t, tt.maxSize, tt.maxBackups)
+			for _, s := range tt.writes {
+				write(t, r, s)
+			}
+			for i, want := range tt.want {
+				name := path
+				if i > 0 {
+					name = r.backupPath(i)
+				}
+				if got := readFile(t, name); got != want {
+					t.Errorf("%s = %q, want %q", filepath.Base(name), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileShared(t *testing.T) {
+	// The app and the daemon both write to the log file.
+	app, path := openTestFile(t, 7, 1)
+	daemon, err := openRotatingFile(path, 7, 1)
+	if err != nil {
+		t.Fatalf("openRotatingFile() returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = daemon.Close() })
+
+	write(t, app, "app1\n")
+	// The app's write counts towards the size, so the daemon rotates here.
+	write(t, daemon, "d1\n")
+	// The app writes to the new file rather than the one moved to .1, and doesn't rotate it again since only
+	// the daemon's line is in it.
+	write(t, app, "a2\n")
+
+	if got, want := readFile(t, path), "d1\na2\n"; got != want {
+		t.Errorf("test.log = %q, want %q", got, want)
+	}
+	if got, want := readFile(t, app.backupPath(1)), "app1\n"; got != want {
+		t.Errorf("test.log.1 = %q, want %q", got, want)
+	}
+}
@@ -5,23 +5,46 @@ import (
 	"claude-squad/config"
 	"claude-squad/daemon"
 	"claude-squad/log"
+	"claude-squad/server"
 	"claude-squad/session"
 	"claude-squad/session/git"
 	"claude-squad/session/tmux"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"runtime/debug"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// version, commit and date describe the build. They're set with -ldflags "-X main.version=...", which goreleaser
+// does for releases.
+var version, commit, date string
+
 var (
 	resetFlag   bool
 	programFlag string
 	autoYesFlag bool
 	daemonFlag  bool
-	rootCmd     = &cobra.Command{
+	jsonFlag    bool
+	pathFlag    string
+	restartFlag bool
+	nameFlag    string
+	promptFlag  string
+	profileFlag string
+
+	templateFlag         string
+	skipProgramCheckFlag bool
+	pruneStatusFlag      string
+	baseBranchFlag       string
+	addrFlag             string
+	logFileFlag          string
+	rootCmd              = &cobra.Command{
 		Use:   "claude-squad",
 		Short: "Claude Squad - A terminal-based session manager",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -29,15 +52,21 @@ var (
 			log.Initialize(daemonFlag)
 			defer log.Close()
 
-			if daemonFlag {
-				err := daemon.RunDaemon()
-				return err
-			}
-
 			cfg, err := config.LoadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+			// LoadConfig already rejected invalid log levels.
+			logLevel, _ := log.ParseLevel(cfg.LogLevel)
+			log.SetLevel(logLevel)
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			if daemonFlag {
+				err := daemon.RunDaemon(cfg.AutoYes || autoYesFlag)
+				return err
+			}
 
 			if resetFlag {
 				storage, err := session.NewStorage()
@@ -68,29 +97,39 @@ var (
 				return nil
 			}
 
-			// Program flag overrides config
-			program := cfg.DefaultProgram
-			if programFlag != "" {
-				program = programFlag
+			// Program and profile flags override config
+			program, err := resolveProgram(cfg)
+			if err != nil {
+				return err
 			}
 			// AutoYes flag overrides config
 			autoYes := cfg.AutoYes
 			if autoYesFlag {
 				autoYes = true
 			}
-			if autoYes {
-				defer func() {
-					if err := daemon.LaunchDaemon(); err != nil {
-						log.ErrorLog.Printf("failed to launch daemon: %v", err)
-					}
-				}()
-			}
+			// Keep answering prompts after exiting if autoyes is on for the app or for any session.
+			defer func() {
+				if !autoYes && !hasAutoYesSessions() {
+					return
+				}
+				if err := daemon.LaunchDaemon(autoYes); err != nil {
+					log.ErrorLog.Printf("failed to launch daemon: %v", err)
+				}
+			}()
 			// Kill any daemon that's running.
 			if err := daemon.StopDaemon(); err != nil {
 				log.ErrorLog.Printf("failed to stop daemon: %v", err)
 			}
 
-			return app.Run(ctx, program, autoYes)
+			return app.Run(ctx, cfg, program, autoYes, pathFlag)
+		},
+	}
+
+	versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit and build date",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(versionString())
 		},
 	}
 
@@ -98,6 +137,9 @@ var (
 		Use:   "debug",
 		Short: "Print debug information like config paths",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
 			cfg, err := config.LoadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
@@ -113,23 +155,751 @@ var (
 			return nil
 		},
 	}
+
+	newCmd = &cobra.Command{
+		Use:   "new",
+		Short: "Create and start a new session without launching the TUI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			if nameFlag == "" {
+				return fmt.Errorf("--name is required")
+			}
+			var template config.Template
+			if templateFlag != "" {
+				if template, err = cfg.GetTemplate(templateFlag); err != nil {
+					return err
+				}
+				// Flags which are given explicitly take precedence over the template.
+				if template.Program != "" && !cmd.Flags().Changed("program") && !cmd.Flags().Changed("profile") {
+					programFlag = template.Program
+				}
+				if template.Path != "" && !cmd.Flags().Changed("path") {
+					pathFlag = template.Path
+				}
+				if promptFlag == "" {
+					promptFlag = template.Prompt
+				}
+			}
+			program, err := resolveProgram(cfg)
+			if err != nil {
+				return err
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instance, err := startSession(cfg, storage, sessionSpec{
+				Name:       nameFlag,
+				Program:    program,
+				Path:       pathFlag,
+				Prompt:     promptFlag,
+				BaseBranch: baseBranchFlag,
+				Tags:       template.Tags,
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(instance.Title)
+			return nil
+		},
+	}
+
+	batchCmd = &cobra.Command{
+		Use:   "batch <file>",
+		Short: "Create and start every session listed in a JSON file without launching the TUI",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+			var specs []sessionSpec
+			if err := json.Unmarshal(content, &specs); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", args[0], err)
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			if failed := runBatch(cfg, storage, specs); failed > 0 {
+				return fmt.Errorf("failed to start %d of %d sessions", failed, len(specs))
+			}
+			return nil
+		},
+	}
+
+	resumeCmd = &cobra.Command{
+		Use:   "resume <title>",
+		Short: "Resume a session and attach to it without launching the TUI",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			// Only restore the requested session rather than every stored one.
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			var data *session.InstanceData
+			for i := range instances {
+				if instances[i].Title == args[0] {
+					data = &instances[i]
+					break
+				}
+			}
+			if data == nil {
+				return fmt.Errorf("no session named '%s'", args[0])
+			}
+
+			instance, err := session.FromInstanceData(*data)
+			if err != nil {
+				return fmt.Errorf("failed to restore session: %w", err)
+			}
+			if instance.Paused() {
+				if err := instance.Resume(); err != nil {
+					return fmt.Errorf("failed to resume session: %w", err)
+				}
+				if err := storage.UpdateInstance(instance); err != nil {
+					return fmt.Errorf("failed to save session: %w", err)
+				}
+			}
+
+			ch, err := instance.Attach()
+			if err != nil {
+				return fmt.Errorf("failed to attach to session: %w", err)
+			}
+			<-ch
+			return nil
+		},
+	}
+
+	attachCmdCmd = &cobra.Command{
+		Use:   "attach-cmd <title>",
+		Short: "Print the tmux command which attaches to a session directly, ex. from another terminal",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			// The tmux session name only depends on the title, so there's no need to restore the session.
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			for _, data := range instances {
+				if data.Title != args[0] {
+					continue
+				}
+				if data.Status == session.Paused {
+					return fmt.Errorf("session '%s' is paused, resume it first", data.Title)
+				}
+				fmt.Println(tmux.NewTmuxSession(data.Title, data.Program).AttachCommand())
+				return nil
+			}
+			return fmt.Errorf("no session named '%s'", args[0])
+		},
+	}
+
+	pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Kill all sessions with a status, ex. every paused session",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			status, err := session.ParseStatus(pruneStatusFlag)
+			if err != nil {
+				return err
+			}
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+
+			var pruned []string
+			var errs []error
+			for _, data := range instances {
+				if data.Status != status {
+					continue
+				}
+				pruned = append(pruned, data.Title)
+				instance, err := session.FromInstanceData(data)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", data.Title, err))
+					continue
+				}
+				if err := instance.Kill(); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", data.Title, err))
+				}
+			}
+			if err := storage.DeleteInstances(pruned); err != nil {
+				return fmt.Errorf("failed to delete instances: %w", err)
+			}
+
+			fmt.Printf("Pruned %d %s sessions\n", len(pruned), status)
+			if len(errs) > 0 {
+				return fmt.Errorf("failed to clean up %d sessions: %w", len(errs), errors.Join(errs...))
+			}
+			return nil
+		},
+	}
+
+	exportCmd = &cobra.Command{
+		Use:   "export <file>",
+		Short: "Write all saved sessions to a JSON file, ex. to move them to another machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			data, err := json.MarshalIndent(instances, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal instances: %w", err)
+			}
+			// Prompts can contain anything, so only the user can read the file.
+			if err := os.WriteFile(args[0], data, 0600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", args[0], err)
+			}
+			fmt.Printf("Exported %d sessions to %s\n", len(instances), args[0])
+			return nil
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Add the sessions from a file written by export. They're imported paused",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+			var instances []session.InstanceData
+			if err := json.Unmarshal(content, &instances); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", args[0], err)
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			titles, err := storage.ImportInstanceData(instances)
+			if err != nil {
+				return fmt.Errorf("failed to import instances: %w", err)
+			}
+			for i, title := range titles {
+				if title != instances[i].Title {
+					fmt.Printf("Imported %s as %s, since the title was taken\n", instances[i].Title, title)
+				}
+				if _, err := os.Stat(instances[i].Worktree.RepoPath); err != nil {
+					fmt.Printf("Warning: the repository of %s, %s, doesn't exist on this machine\n",
+						title, instances[i].Worktree.RepoPath)
+				}
+			}
+			fmt.Printf("Imported %d sessions. Resume them to recreate their worktrees\n", len(titles))
+			return nil
+		},
+	}
+
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API to list, create and control sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := configureSessions(cfg); err != nil {
+				return err
+			}
+
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			tokenPath, err := server.TokenPath()
+			if err != nil {
+				return err
+			}
+			token, err := server.LoadOrCreateToken(tokenPath)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Serving the API on http://%s\n", addrFlag)
+			fmt.Printf("Requests must send the token from %s as \"Authorization: Bearer <token>\"\n", tokenPath)
+			return server.New(storage, cfg, addrFlag, token).ListenAndServe()
+		},
+	}
+
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Print the status of the autoyes daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Initialize(false)
+			defer log.Close()
+
+			if restartFlag {
+				previous, err := daemon.GetStatus()
+				if err != nil {
+					return fmt.Errorf("failed to get daemon status: %w", err)
+				}
+				if err := daemon.StopDaemon(); err != nil {
+					return fmt.Errorf("failed to stop daemon: %w", err)
+				}
+				// Keep answering every session's prompts if the daemon did. It reads auto_yes from the config itself.
+				if err := daemon.LaunchDaemon(previous.AutoYes); err != nil {
+					return fmt.Errorf("failed to launch daemon: %w", err)
+				}
+				fmt.Println("Daemon has been restarted")
+			}
+
+			status, err := daemon.GetStatus()
+			if err != nil {
+				return fmt.Errorf("failed to get daemon status: %w", err)
+			}
+			if !status.Running {
+				fmt.Println("Daemon is not running")
+				return nil
+			}
+			fmt.Printf("Daemon is running (PID: %d)\n", status.PID)
+			fmt.Printf("Uptime: %s\n", status.Uptime.Round(time.Second))
+			fmt.Printf("Sessions in autoyes mode: %d\n", status.ManagedSessions)
+			return nil
+		},
+	}
+
+	statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Print your usage stats. They're only stored locally",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stats, err := session.LoadStats()
+			if err != nil {
+				return err
+			}
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+			// The runtime of sessions which are still running hasn't been recorded yet.
+			runtime := stats.Runtime
+			for _, instance := range instances {
+				if instance.Status != session.Paused && !instance.RunningSince.IsZero() {
+					runtime += time.Since(instance.RunningSince)
+				}
+			}
+
+			if stats.Since.IsZero() {
+				fmt.Println("No usage recorded yet")
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "Since:\t%s\n", stats.Since.Format("2006-01-02"))
+			fmt.Fprintf(w, "Sessions created:\t%d\n", stats.SessionsCreated)
+			fmt.Fprintf(w, "Prompts sent:\t%d\n", stats.PromptsSent)
+			fmt.Fprintf(w, "Pushes:\t%d\n", stats.Pushes)
+			fmt.Fprintf(w, "Pauses:\t%d\n", stats.Pauses)
+			fmt.Fprintf(w, "Resumes:\t%d\n", stats.Resumes)
+			fmt.Fprintf(w, "Agent runtime:\t%s\n", runtime.Round(time.Minute))
+			return w.Flush()
+		},
+	}
+
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Print all saved sessions without launching the TUI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storage, err := session.NewStorage()
+			if err != nil {
+				return fmt.Errorf("failed to initialize storage: %w", err)
+			}
+			// Load the raw data so we don't restore tmux sessions just to list them.
+			instances, err := storage.LoadInstanceData()
+			if err != nil {
+				return fmt.Errorf("failed to load instances: %w", err)
+			}
+
+			if jsonFlag {
+				data, err := json.MarshalIndent(instances, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal instances: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if len(instances) == 0 {
+				fmt.Println("No sessions found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TITLE\tSTATUS\tBRANCH\tCREATED\tWORKTREE\tDIFF")
+			for _, instance := range instances {
+				created := "-"
+				if !instance.CreatedAt.IsZero() {
+					created = instance.CreatedAt.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t+%d,-%d\n",
+					instance.Title,
+					instance.Status,
+					instance.Branch,
+					created,
+					instance.Worktree.WorktreePath,
+					instance.DiffStats.Added,
+					instance.DiffStats.Removed,
+				)
+			}
+			return w.Flush()
+		},
+	}
 )
 
 func init() {
 	rootCmd.Flags().BoolVar(&resetFlag, "reset", false, "Reset all stored instances")
 	rootCmd.Flags().StringVarP(&programFlag, "program", "p", "",
 		"Program to run in new instances (e.g. 'aider --model ollama_chat/gemma3:1b')")
+	rootCmd.Flags().StringVar(&profileFlag, "profile", "", "Profile from the config to run in new instances")
+	rootCmd.MarkFlagsMutuallyExclusive("program", "profile")
 	rootCmd.Flags().BoolVarP(&autoYesFlag, "autoyes", "y", false,
 		"[experimental] If enabled, all instances will automatically accept prompts")
+	rootCmd.Flags().StringVar(&pathFlag, "path", ".", "Default repository path for new instances")
 	rootCmd.Flags().BoolVar(&daemonFlag, "daemon", false, "Run a program that loads all sessions"+
 		" and runs autoyes mode on them.")
+	rootCmd.Flags().BoolVar(&skipProgramCheckFlag, "skip-program-check", false,
+		"Don't check that programs are installed before starting them, ex. for shell aliases")
 	// Hide the daemonFlag as it's only for internal use
 	err := rootCmd.Flags().MarkHidden("daemon")
 	if err != nil {
 		panic(err)
 	}
 
+	listCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print the sessions as JSON")
+
+	rootCmd.PersistentFlags().StringVar(&logFileFlag, "log-file", "",
+		"Path of the log file (defaults to log_file from the config, or claudesquad.log in the temp directory)")
+	rootCmd.Version = versionString()
+	rootCmd.SetVersionTemplate("{{.Version}}\n")
+	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(statsCmd)
+
+	newCmd.Flags().StringVar(&nameFlag, "name", "", "Name of the session")
+	newCmd.Flags().StringVarP(&programFlag, "program", "p", "", "Program to run in the session (defaults to the config)")
+	newCmd.Flags().StringVar(&profileFlag, "profile", "", "Profile from the config to run in the session")
+	newCmd.MarkFlagsMutuallyExclusive("program", "profile")
+	newCmd.Flags().StringVar(&pathFlag, "path", ".", "Path of the repository to create the session in")
+	newCmd.Flags().StringVar(&promptFlag, "prompt", "", "Initial prompt to send to the session")
+	newCmd.Flags().StringVar(&templateFlag, "template", "", "Template from the config to create the session from")
+	newCmd.Flags().StringVar(&baseBranchFlag, "base-branch", "", "Branch to start the session from (defaults to the current branch)")
+	newCmd.Flags().BoolVar(&skipProgramCheckFlag, "skip-program-check", false,
+		"Don't check that the program is installed before starting it")
+	rootCmd.AddCommand(newCmd)
+
+	batchCmd.Flags().BoolVar(&skipProgramCheckFlag, "skip-program-check", false,
+		"Don't check that programs are installed before starting them")
+	rootCmd.AddCommand(batchCmd)
+
+	resumeCmd.Flags().BoolVar(&skipProgramCheckFlag, "skip-program-check", false,
+		"Don't check that the program is installed before starting it")
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(attachCmdCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	pruneCmd.Flags().StringVar(&pruneStatusFlag, "status", "paused", "Status of the sessions to kill: paused, ready, running or loading")
+	rootCmd.AddCommand(pruneCmd)
+
+	serveCmd.Flags().StringVar(&addrFlag, "addr", server.DefaultAddr,
+		"Address to listen on. Anyone who can reach it can control your sessions")
+	serveCmd.Flags().BoolVar(&skipProgramCheckFlag, "skip-program-check", false,
+		"Don't check that programs are installed before starting them")
+	rootCmd.AddCommand(serveCmd)
+
+	statusCmd.Flags().BoolVar(&restartFlag, "restart", false, "Stop and relaunch the daemon")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// hasAutoYesSessions returns whether any stored session has autoyes on.
+func hasAutoYesSessions() bool {
+	storage, err := session.NewStorage()
+	if err != nil {
+		log.ErrorLog.Printf("failed to initialize storage: %v", err)
+		return false
+	}
+	instances, err := storage.LoadInstanceData()
+	if err != nil {
+		log.ErrorLog.Printf("failed to load instances: %v", err)
+		return false
+	}
+	for _, data := range instances {
+		if data.AutoYes {
+			return true
+		}
+	}
+	return false
+}
+
+// versionString describes the build for the version command and --version. Builds without -ldflags, ex. with go
+// install, fall back to the commit and time recorded by the go toolchain.
+func versionString() string {
+	v, c, d := version, commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && c == "":
+				c = setting.Value
+			case setting.Key == "vcs.time" && d == "":
+				d = setting.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "dev"
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+	return fmt.Sprintf("claude-squad %s (commit %s, built %s)", v, c, d)
+}
+
+// resolveProgram returns the program to run in new instances. --program and --profile override the default
+// program from the config.
+func resolveProgram(cfg *config.Config) (string, error) {
+	if programFlag != "" {
+		return programFlag, tmux.ValidateProgram(programFlag)
+	}
+	if profileFlag != "" {
+		program, err := cfg.GetProfile(profileFlag)
+		if err != nil {
+			return "", err
+		}
+		return program, tmux.ValidateProgram(program)
+	}
+	return cfg.DefaultProgram, nil
+}
+
+// sessionSpec describes a session for startSession to create.
+type sessionSpec struct {
+	Name string `json:"name"`
+	// Program must already be resolved, ex. from a profile.
+	Program    string   `json:"program"`
+	Path       string   `json:"path"`
+	Prompt     string   `json:"prompt"`
+	BaseBranch string   `json:"base_branch"`
+	Tags       []string `json:"tags"`
+}
+
+// runBatch starts the sessions described by specs, printing a line for each, and returns how many failed. It
+// keeps going after a failure so one bad entry doesn't stop the rest of the squad from starting.
+func runBatch(cfg *config.Config, storage *session.Storage, specs []sessionSpec) int {
+	failed := 0
+	for _, spec := range specs {
+		if spec.Name == "" {
+			fmt.Println("FAILED: a session in the file has no name")
+			failed++
+			continue
+		}
+		if spec.Program == "" {
+			spec.Program = cfg.DefaultProgram
+		}
+		if spec.Path == "" {
+			spec.Path = "."
+		}
+		if _, err := startSession(cfg, storage, spec); err != nil {
+			fmt.Printf("FAILED %s: %v\n", spec.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("OK %s\n", spec.Name)
+	}
+	return failed
+}
+
+// startSession creates and starts the session described by spec and saves it. The default prompt from the config
+// and then the spec's prompt are sent to it.
+func startSession(cfg *config.Config, storage *session.Storage, spec sessionSpec) (*session.Instance, error) {
+	repoRoot, err := git.FindRepoRoot(spec.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check before starting so we don't create a worktree and tmux session we can't store.
+	existing, err := storage.LoadInstanceData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+	// 0 means there's no limit, like in the TUI.
+	if cfg.MaxInstances > 0 && len(existing) >= cfg.MaxInstances {
+		return nil, fmt.Errorf("you can't create more than %d instances", cfg.MaxInstances)
+	}
+	for _, data := range existing {
+		if data.Title == spec.Name {
+			return nil, fmt.Errorf("a session named '%s' already exists", spec.Name)
+		}
+	}
+
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   spec.Name,
+		Path:    repoRoot,
+		Program: spec.Program,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance: %w", err)
+	}
+	instance.BaseBranch = spec.BaseBranch
+	if err := instance.Start(true); err != nil {
+		return nil, fmt.Errorf("failed to start instance: %w", err)
+	}
+	instance.Prompt = spec.Prompt
+	instance.Tags = spec.Tags
+	if err := storage.AddInstance(instance); err != nil {
+		if killErr := instance.Kill(); killErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, killErr)
+		}
+		return nil, fmt.Errorf("failed to save instance: %w", err)
+	}
+	if cfg.DefaultPromptTemplate != "" {
+		if err := instance.SendPromptTemplate(cfg.DefaultPromptTemplate); err != nil {
+			return nil, fmt.Errorf("failed to send default prompt: %w", err)
+		}
+	}
+	if spec.Prompt != "" {
+		if err := instance.SendPrompt(spec.Prompt); err != nil {
+			return nil, fmt.Errorf("failed to send prompt: %w", err)
+		}
+	}
+	return instance, nil
+}
+
+// configureSessions applies the session, tmux, git worktree and log file settings from the config.
+func configureSessions(cfg *config.Config) error {
+	logFile := cfg.LogFile
+	if logFileFlag != "" {
+		logFile = logFileFlag
+	}
+	log.SetRotation(int64(cfg.LogMaxSizeMB)*1024*1024, cfg.LogMaxFiles)
+	if err := log.SetFile(logFile); err != nil {
+		return err
+	}
+	if err := git.SetWorktreeBaseDir(cfg.WorktreeBaseDir); err != nil {
+		return err
+	}
+	git.SetCommitAuthor(cfg.CommitAuthorName, cfg.CommitAuthorEmail)
+	tmux.SkipProgramCheck = skipProgramCheckFlag
+	tmux.ExtraPaneCommand = cfg.ExtraPaneCommand
+	session.AutoCommitOnPause = cfg.AutoCommitOnPause
+	session.TranscriptDir = cfg.TranscriptDir
+	session.TranscriptKeepANSI = cfg.TranscriptKeepANSI
+	session.SetMaxTitleLength(cfg.MaxTitleLength)
+	session.SetPreviewHistoryLines(cfg.PreviewHistoryLines)
+	tmux.SessionEnv = cfg.GetEnv()
+	if err := tmux.SetPromptPatterns(cfg.PromptPatterns); err != nil {
+		return err
+	}
+	if err := session.SetAutoYesDenyPatterns(cfg.AutoYesDenyPatterns); err != nil {
+		return err
+	}
+	if err := tmux.SetConfigFile(cfg.TmuxConfigFile); err != nil {
+		return err
+	}
+	tmux.SetStartMaxAttempts(cfg.TmuxStartAttempts)
+	tmux.AutoAcceptTrust = cfg.AutoAcceptTrust
+	tmux.SetTrustScreenPolling(
+		time.Duration(cfg.TrustScreenTimeoutMs)*time.Millisecond,
+		time.Duration(cfg.TrustScreenPollIntervalMs)*time.Millisecond,
+	)
+	return tmux.SetPrefix(cfg.TmuxPrefix)
 }
 
 func main() {
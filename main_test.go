@@ -0,0 +1,98 @@
+package main
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"claude-squad/session/tmux"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	code := m.Run()
+	log.Close()
+	os.Exit(code)
+}
+
+// newTestEnv returns the default config and a storage in a temporary home directory, with sessions started on
+// a tmux server of the test's own, and a git repository to start them in.
+func newTestEnv(t *testing.T) (*config.Config, *session.Storage, string) {
+	for _, program := range []string{"tmux", "git", "sh"} {
+		if _, err := exec.LookPath(program); err != nil {
+			t.Skipf("%s is not installed", program)
+		}
+	}
+	t.Setenv("HOME", t.TempDir())
+	// Keep the sessions away from the user's tmux server.
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Cleanup(func() { _ = exec.Command("tmux", "kill-server").Run() })
+	origTrust := tmux.AutoAcceptTrust
+	tmux.AutoAcceptTrust = false
+	t.Cleanup(func() { tmux.AutoAcceptTrust = origTrust })
+
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s (%v)", strings.Join(args, " "), output, err)
+		}
+	}
+
+	storage, err := session.NewStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DefaultProgram = "sh"
+	return cfg, storage, repo
+}
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxInstances int
+		wantFailed   int
+	}{
+		{name: "unlimited", maxInstances: 0, wantFailed: 0},
+		{name: "limited", maxInstances: 1, wantFailed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, storage, repo := newTestEnv(t)
+			cfg.MaxInstances = tt.maxInstances
+
+			specs := []sessionSpec{{Name: "first", Path: repo}, {Name: "second", Path: repo}}
+			if failed := runBatch(cfg, storage, specs); failed != tt.wantFailed {
+				t.Errorf("runBatch() failed to start %d sessions, want %d", failed, tt.wantFailed)
+			}
+			data, err := storage.LoadInstanceData()
+			if err != nil {
+				t.Fatalf("failed to load instances: %v", err)
+			}
+			if want := len(specs) - tt.wantFailed; len(data) != want {
+				t.Errorf("%d sessions were stored, want %d", len(data), want)
+			}
+		})
+	}
+}
+
+func TestStartSessionInstanceLimit(t *testing.T) {
+	cfg, storage, repo := newTestEnv(t)
+	cfg.MaxInstances = 1
+	if _, err := startSession(cfg, storage, sessionSpec{Name: "first", Program: "sh", Path: repo}); err != nil {
+		t.Fatalf("startSession() returned error: %v", err)
+	}
+	_, err := startSession(cfg, storage, sessionSpec{Name: "second", Program: "sh", Path: repo})
+	if err == nil || !strings.Contains(err.Error(), "more than 1 instances") {
+		t.Errorf("startSession() past the limit returned error %v, want the limit to be enforced", err)
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Sound plays a sound by running command, ex. "afplay /System/Library/Sounds/Glass.aiff". An empty command rings
+// the terminal bell.
+func Sound(command string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return bell()
+	}
+	if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to play sound: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// Send shows a desktop notification. If no notification tool is available, it rings the terminal bell instead.
+func Send(title, message string) error {
+	cmd := notificationCommand(title, message)
+	if cmd == nil {
+		return bell()
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		// Fall back to the bell so the user still hears about it.
+		_ = bell()
+		return fmt.Errorf("failed to send notification: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// notificationCommand returns the command which shows a notification on this platform, or nil if there's none.
+func notificationCommand(title, message string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName("text")
+$text.Item(0).AppendChild($template.CreateTextNode(%s)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("claude-squad").Show([Windows.UI.Notifications.ToastNotification]::new($template))`,
+			powerShellString(title), powerShellString(message))
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return nil
+		}
+		return exec.Command("notify-send", title, message)
+	}
+}
+
+// bell rings the terminal bell.
+func bell() error {
+	_, err := os.Stdout.Write([]byte("\a"))
+	return err
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// powerShellString quotes s as a single quoted PowerShell string literal.
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
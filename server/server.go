@@ -0,0 +1,402 @@
+// Package server exposes the stored sessions over a local HTTP API, so other tools can inspect and control them.
+package server
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"claude-squad/session/git"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is the address the server listens on by default. It's only reachable from this machine.
+const DefaultAddr = "127.0.0.1:7433"
+
+// errNotFound is returned when there's no stored session with the requested title.
+var errNotFound = errors.New("session not found")
+
+// Server handles the API requests. Sessions are loaded from and saved to storage on every request, like the CLI
+// commands do, so changes made by the TUI are picked up.
+type Server struct {
+	storage *session.Storage
+	cfg     *config.Config
+	// addr is the address the server listens on. Requests must be addressed to it.
+	addr string
+	// token is the bearer token every request must carry.
+	token string
+	// mu serializes requests which change sessions so they don't overwrite each other's changes to storage.
+	mu sync.Mutex
+}
+
+// New creates a server for the sessions in storage which listens on addr and accepts requests carrying token.
+func New(storage *session.Storage, cfg *config.Config, addr, token string) *Server {
+	return &Server{storage: storage, cfg: cfg, addr: addr, token: token}
+}
+
+// ListenAndServe serves the API until it fails.
+func (s *Server) ListenAndServe() error {
+	log.InfoLog.Printf("serving api on %s", s.addr)
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// Handler returns the handler for the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", s.handleList)
+	mux.HandleFunc("POST /sessions", s.handleCreate)
+	mux.HandleFunc("GET /sessions/{title}", s.handleGet)
+	mux.HandleFunc("DELETE /sessions/{title}", s.handleKill)
+	mux.HandleFunc("POST /sessions/{title}/pause", s.handlePause)
+	mux.HandleFunc("POST /sessions/{title}/resume", s.handleResume)
+	mux.HandleFunc("POST /sessions/{title}/prompt", s.handlePrompt)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests which don't carry the token, and requests a browser could have been tricked into
+// sending: ones from another origin, or addressed to another host name which resolves to this machine.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("unexpected host %q", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !s.allowedHost(u.Host) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests aren't allowed"))
+				return
+			}
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid api token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost returns true if host, from a Host or Origin header, names the address the server listens on. Other
+// names are only allowed as IP addresses or localhost, since a name an attacker controls could be made to resolve
+// to this machine.
+func (s *Server) allowedHost(host string) bool {
+	if host == s.addr {
+		return true
+	}
+	_, listenPort, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return false
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil || port != listenPort {
+		return false
+	}
+	return name == "localhost" || net.ParseIP(name) != nil
+}
+
+// decodeJSON decodes the JSON body of r into v. Other content types are refused, so the request can't have been
+// sent by a plain HTML form.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("the request body must be application/json"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// sessionResponse is how a session is represented in responses.
+type sessionResponse struct {
+	Title     string    `json:"title"`
+	Path      string    `json:"path"`
+	Branch    string    `json:"branch"`
+	Status    string    `json:"status"`
+	Program   string    `json:"program"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Added     int       `json:"added"`
+	Removed   int       `json:"removed"`
+}
+
+func newSessionResponse(data session.InstanceData) sessionResponse {
+	return sessionResponse{
+		Title:     data.Title,
+		Path:      data.Path,
+		Branch:    data.Branch,
+		Status:    data.Status.String(),
+		Program:   data.Program,
+		Tags:      data.Tags,
+		CreatedAt: data.CreatedAt,
+		UpdatedAt: data.UpdatedAt,
+		Added:     data.DiffStats.Added,
+		Removed:   data.DiffStats.Removed,
+	}
+}
+
+// handleList responds with every stored session. Statuses and diff stats are as of the last save.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	instances, err := s.storage.LoadInstanceData()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sessions := make([]sessionResponse, len(instances))
+	for i, data := range instances {
+		sessions[i] = newSessionResponse(data)
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	data, err := s.loadData(r.PathValue("title"))
+	if err != nil {
+		writeLoadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSessionResponse(*data))
+}
+
+// createRequest is the body of a request to create a session.
+type createRequest struct {
+	Title string `json:"title"`
+	// Path is the repository to create the session in.
+	Path string `json:"path"`
+	// Profile is the name of the profile from the config to run. The default program is run if it's empty. Other
+	// programs can't be run, so the API can't be used to run arbitrary commands.
+	Profile string `json:"profile"`
+	Prompt  string `json:"prompt"`
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Title == "" || req.Path == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("title and path are required"))
+		return
+	}
+	program := s.cfg.DefaultProgram
+	if req.Profile != "" {
+		var err error
+		if program, err = s.cfg.GetProfile(req.Profile); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	repoRoot, err := git.FindRepoRoot(req.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check before starting so we don't create a worktree and tmux session we can't store.
+	existing, err := s.storage.LoadInstanceData()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if s.cfg.MaxInstances > 0 && len(existing) >= s.cfg.MaxInstances {
+		writeError(w, http.StatusConflict, fmt.Errorf("you can't create more than %d instances", s.cfg.MaxInstances))
+		return
+	}
+	for _, data := range existing {
+		if data.Title == req.Title {
+			writeError(w, http.StatusConflict, fmt.Errorf("a session named '%s' already exists", req.Title))
+			return
+		}
+	}
+
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:   req.Title,
+		Path:    repoRoot,
+		Program: program,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := instance.Start(true); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to start instance: %w", err))
+		return
+	}
+	instance.Prompt = req.Prompt
+	if err := s.storage.AddInstance(instance); err != nil {
+		if killErr := instance.Kill(); killErr != nil {
+			err = fmt.Errorf("%v (cleanup error: %v)", err, killErr)
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save instance: %w", err))
+		return
+	}
+	if s.cfg.DefaultPromptTemplate != "" {
+		if err := instance.SendPromptTemplate(s.cfg.DefaultPromptTemplate); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to send default prompt: %w", err))
+			return
+		}
+	}
+	if req.Prompt != "" {
+		if err := instance.SendPrompt(req.Prompt); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to send prompt: %w", err))
+			return
+		}
+	}
+	writeJSON(w, http.StatusCreated, newSessionResponse(instance.ToInstanceData()))
+}
+
+func (s *Server) handleKill(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instance, err := s.load(r.PathValue("title"))
+	if err != nil {
+		writeLoadError(w, err)
+		return
+	}
+	// Remove it from storage even if cleaning up fails, like the TUI does, and report the failure.
+	killErr := instance.Kill()
+	if err := s.storage.DeleteInstances([]string{instance.Title}); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if killErr != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("session removed, but cleaning it up failed: %w", killErr))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.update(w, r, func(instance *session.Instance) error {
+		return instance.Pause()
+	})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.update(w, r, func(instance *session.Instance) error {
+		return instance.Resume()
+	})
+}
+
+// promptRequest is the body of a request to send a prompt to a session.
+type promptRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	var req promptRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("prompt cannot be empty"))
+		return
+	}
+	s.update(w, r, func(instance *session.Instance) error {
+		if instance.Paused() {
+			return fmt.Errorf("cannot send a prompt to a paused session")
+		}
+		if err := instance.SendPrompt(req.Prompt); err != nil {
+			return err
+		}
+		// Remember the first prompt so it can be replayed when the instance is duplicated.
+		if instance.Prompt == "" {
+			instance.Prompt = req.Prompt
+		}
+		return nil
+	})
+}
+
+// update loads the session named in the request, applies change to it and saves it. The session is saved even if
+// change fails, since it may have changed anyway, ex. pausing with auto_commit_on_pause.
+func (s *Server) update(w http.ResponseWriter, r *http.Request, change func(instance *session.Instance) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instance, err := s.load(r.PathValue("title"))
+	if err != nil {
+		writeLoadError(w, err)
+		return
+	}
+	changeErr := change(instance)
+	if err := s.storage.UpdateInstance(instance); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if changeErr != nil {
+		writeError(w, http.StatusConflict, changeErr)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSessionResponse(instance.ToInstanceData()))
+}
+
+// loadData returns the stored data of the session with the given title without restoring it.
+func (s *Server) loadData(title string) (*session.InstanceData, error) {
+	instances, err := s.storage.LoadInstanceData()
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		if instances[i].Title == title {
+			return &instances[i], nil
+		}
+	}
+	return nil, errNotFound
+}
+
+// load restores the session with the given title, without restoring the other stored sessions.
+func (s *Server) load(title string) (*session.Instance, error) {
+	data, err := s.loadData(title)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := session.FromInstanceData(*data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore session: %w", err)
+	}
+	return instance, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.WarningLog.Printf("could not write api response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeLoadError responds with the error from load or loadData.
+func writeLoadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
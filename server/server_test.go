@@ -0,0 +1,284 @@
+package server
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"claude-squad/session/tmux"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testAddr  = "127.0.0.1:7433"
+	testToken = "secret"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	code := m.Run()
+	log.Close()
+	os.Exit(code)
+}
+
+// newTestServer returns a server whose storage and config directory are in a temporary home directory.
+func newTestServer(t *testing.T) *Server {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	storage, err := session.NewStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DefaultProgram = "sh"
+	cfg.Profiles = map[string]string{"shell": "sh"}
+	return New(storage, cfg, testAddr, testToken)
+}
+
+// do sends a request to the server as a well-behaved client would. change can alter it before it's sent.
+func do(s *Server, method, path, body string, change func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Host = testAddr
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if change != nil {
+		change(r)
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestAuthorize(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		change func(r *http.Request)
+		want   int
+	}{
+		{
+			name:   "authorized",
+			method: http.MethodGet,
+			want:   http.StatusOK,
+		},
+		{
+			name:   "localhost",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Host = "localhost:7433" },
+			want:   http.StatusOK,
+		},
+		{
+			name:   "same origin",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Header.Set("Origin", "http://"+testAddr) },
+			want:   http.StatusOK,
+		},
+		{
+			name:   "no token",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Header.Del("Authorization") },
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") },
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "rebound host name",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Host = "attacker.example:7433" },
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "other port",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Host = "127.0.0.1:80" },
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "cross origin",
+			method: http.MethodGet,
+			change: func(r *http.Request) { r.Header.Set("Origin", "https://attacker.example") },
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "form body",
+			method: http.MethodPost,
+			body:   `{"title": "test", "path": "."}`,
+			change: func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+			want:   http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "unknown profile",
+			method: http.MethodPost,
+			body:   `{"title": "test", "path": ".", "profile": "rm -rf ~"}`,
+			want:   http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(s, tt.method, "/sessions", tt.body, tt.change); w.Code != tt.want {
+				t.Errorf("%s /sessions = %d (%s), want %d", tt.method, w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_token")
+	token, err := LoadOrCreateToken(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateToken() returned error: %v", err)
+	}
+	if len(token) < 32 {
+		t.Errorf("LoadOrCreateToken() = %q, want a long random token", token)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("token file wasn't written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file permissions = %o, want 600", perm)
+	}
+	again, err := LoadOrCreateToken(path)
+	if err != nil || again != token {
+		t.Errorf("LoadOrCreateToken() = %q, %v the second time, want %q", again, err, token)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateToken(path); err == nil {
+		t.Error("LoadOrCreateToken() accepted a token file other users can read")
+	}
+}
+
+// newTestRepo creates a git repository with one commit.
+func newTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s (%v)", strings.Join(args, " "), output, err)
+		}
+	}
+	return dir
+}
+
+// useTestSessions prepares for starting real sessions, on a tmux server of the test's own. The test is skipped if
+// a program they need isn't installed.
+func useTestSessions(t *testing.T) {
+	for _, program := range []string{"tmux", "git", "sh"} {
+		if _, err := exec.LookPath(program); err != nil {
+			t.Skipf("%s is not installed", program)
+		}
+	}
+	// Keep the sessions on their own tmux server, away from the user's.
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	t.Cleanup(func() { _ = exec.Command("tmux", "kill-server").Run() })
+	// Pausing would otherwise push to the remote, and the test repository has none.
+	origAutoCommit := session.AutoCommitOnPause
+	session.AutoCommitOnPause = true
+	t.Cleanup(func() { session.AutoCommitOnPause = origAutoCommit })
+	tmux.AutoAcceptTrust = false
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	useTestSessions(t)
+	s := newTestServer(t)
+
+	repo := newTestRepo(t)
+	body, _ := json.Marshal(createRequest{Title: "api-test", Path: repo, Profile: "shell"})
+	w := do(s, http.MethodPost, "/sessions", string(body), nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /sessions = %d (%s), want %d", w.Code, w.Body, http.StatusCreated)
+	}
+	t.Cleanup(func() {
+		if instance, err := s.load("api-test"); err == nil {
+			_ = instance.Kill()
+		}
+	})
+	var created sessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if created.Program != "sh" {
+		t.Errorf("created session runs %q, want the profile's program %q", created.Program, "sh")
+	}
+
+	if w := do(s, http.MethodPost, "/sessions", string(body), nil); w.Code != http.StatusConflict {
+		t.Errorf("POST /sessions with a taken title = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := do(s, http.MethodPost, "/sessions/api-test/prompt", `{"prompt": "echo hello"}`, nil); w.Code != http.StatusOK {
+		t.Errorf("POST /sessions/api-test/prompt = %d (%s), want %d", w.Code, w.Body, http.StatusOK)
+	}
+	if w := do(s, http.MethodPost, "/sessions/api-test/prompt", `{"prompt": ""}`, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("POST /sessions/api-test/prompt with an empty prompt = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = do(s, http.MethodPost, "/sessions/api-test/pause", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /sessions/api-test/pause = %d (%s), want %d", w.Code, w.Body, http.StatusOK)
+	}
+	var paused sessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &paused); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if paused.Status != session.Paused.String() {
+		t.Errorf("paused session has status %q, want %q", paused.Status, session.Paused.String())
+	}
+	if w := do(s, http.MethodPost, "/sessions/api-test/prompt", `{"prompt": "echo hello"}`, nil); w.Code != http.StatusConflict {
+		t.Errorf("POST /sessions/api-test/prompt to a paused session = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	if w := do(s, http.MethodDelete, "/sessions/api-test", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE /sessions/api-test = %d (%s), want %d", w.Code, w.Body, http.StatusNoContent)
+	}
+	if w := do(s, http.MethodGet, "/sessions/api-test", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET /sessions/api-test after killing it = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(s, http.MethodDelete, "/sessions/api-test", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE /sessions/api-test twice = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateInstanceLimit(t *testing.T) {
+	useTestSessions(t)
+	s := newTestServer(t)
+	repo := newTestRepo(t)
+	create := func(title string) int {
+		body, _ := json.Marshal(createRequest{Title: title, Path: repo})
+		return do(s, http.MethodPost, "/sessions", string(body), nil).Code
+	}
+
+	// 0 means there's no limit.
+	s.cfg.MaxInstances = 0
+	for _, title := range []string{"first", "second"} {
+		if code := create(title); code != http.StatusCreated {
+			t.Errorf("POST /sessions for %s without a limit = %d, want %d", title, code, http.StatusCreated)
+		}
+	}
+	s.cfg.MaxInstances = 2
+	if code := create("third"); code != http.StatusConflict {
+		t.Errorf("POST /sessions past the limit = %d, want %d", code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"claude-squad/config"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// TokenPath returns the path of the file holding the api token.
+func TokenPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "api_token"), nil
+}
+
+// LoadOrCreateToken reads the api token from path, or generates one and writes it there if the file doesn't exist.
+// The file must only be accessible by the user, since anyone who can read it can run sessions.
+func LoadOrCreateToken(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createToken(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read api token: %w", err)
+	}
+	// Windows doesn't have unix permissions, and the file is in the user's profile.
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read api token: %w", err)
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			return "", fmt.Errorf("api token file %s can be read by other users, run chmod 600 on it", path)
+		}
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("api token file %s is empty", path)
+	}
+	return token, nil
+}
+
+func createToken(path string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// O_EXCL so a token written by another process at the same time isn't overwritten.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return LoadOrCreateToken(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write api token: %w", err)
+	}
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write api token: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write api token: %w", err)
+	}
+	return token, nil
+}
@@ -2,8 +2,10 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -13,6 +15,10 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
+// ErrBaselineMissing is returned in DiffStats.Error when the baseline given to DiffSince no longer exists, ex.
+// because it was garbage collected before it was pinned.
+var ErrBaselineMissing = errors.New("diff baseline no longer exists")
+
 // DiffStats holds statistics about the changes in a diff
 type DiffStats struct {
 	// Content is the full diff content
@@ -21,6 +27,9 @@ type DiffStats struct {
 	Added int
 	// Removed is the number of removed lines
 	Removed int
+	// TotalAdded and TotalRemoved count the lines in all files, including the ones hidden by a filter
+	TotalAdded   int
+	TotalRemoved int
 	// Error holds any error that occurred during diff computation
 	// This allows propagating setup errors (like missing base commit) without breaking the flow
 	Error error
@@ -30,9 +39,90 @@ func (d *DiffStats) IsEmpty() bool {
 	return d.Added == 0 && d.Removed == 0 && d.Content == ""
 }
 
-// Diff returns the git diff between the worktree and the base branch along with statistics
-func (g *GitWorktree) Diff() *DiffStats {
-	worktree, baseTree, stats := g.prepareGitObjectsForDiff()
+// DiffFilter selects which files are included in a diff
+type DiffFilter struct {
+	// include and exclude are glob patterns. A file is included if it matches any include pattern, or there are
+	// none, and doesn't match any exclude pattern.
+	include []string
+	exclude []string
+	spec    string
+}
+
+// ParseDiffFilter parses a comma separated list of glob patterns, ex. "*.go,!*_test.go". Patterns starting with
+// "!" exclude matching files. Patterns without a "/" are matched against the file name, others against the path
+// relative to the repository root. An empty spec returns a nil filter, which includes everything.
+func ParseDiffFilter(spec string) (*DiffFilter, error) {
+	filter := &DiffFilter{spec: spec}
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid diff filter pattern %q: %w", pattern, err)
+		}
+		if exclude {
+			filter.exclude = append(filter.exclude, pattern)
+		} else {
+			filter.include = append(filter.include, pattern)
+		}
+	}
+	if len(filter.include) == 0 && len(filter.exclude) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// Matches returns whether the file at path is included. A nil filter matches everything.
+func (f *DiffFilter) Matches(path string) bool {
+	if f == nil {
+		return true
+	}
+	for _, pattern := range f.exclude {
+		if matchPattern(pattern, path) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if matchPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the spec the filter was parsed from
+func (f *DiffFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.spec
+}
+
+func matchPattern(pattern, path string) bool {
+	path = filepath.ToSlash(path)
+	if !strings.Contains(pattern, "/") {
+		path = filepath.Base(path)
+	}
+	matched, _ := filepath.Match(pattern, path)
+	return matched
+}
+
+// Diff returns the git diff between the worktree and the base branch along with statistics. Only files matching
+// filter are included in the content and the Added and Removed counts. filter may be nil to include everything.
+func (g *GitWorktree) Diff(filter *DiffFilter) *DiffStats {
+	return g.DiffSince("", filter)
+}
+
+// DiffSince is like Diff, but compares the worktree with baseline, a tree written by SnapshotTree, instead of the
+// base branch. An empty baseline compares with the base branch.
+func (g *GitWorktree) DiffSince(baseline string, filter *DiffFilter) *DiffStats {
+	worktree, baseTree, stats := g.prepareGitObjectsForDiff(baseline)
 	if stats.Error != nil {
 		return stats
 	}
@@ -55,6 +145,7 @@ func (g *GitWorktree) Diff() *DiffStats {
 		}
 
 		filePath := paths[i]
+		included := filter.Matches(filePath)
 
 		// Get the file content from the worktree
 		var currentContent []byte
@@ -76,16 +167,24 @@ func (g *GitWorktree) Diff() *DiffStats {
 			}
 			baseContent = []byte(content)
 		}
+		// The file may differ from HEAD but not from the base, ex. when it's unchanged since the baseline.
+		if bytes.Equal(baseContent, currentContent) {
+			continue
+		}
+
+		// Files hidden by the filter are still diffed since they count towards the totals.
+		var fileOutput bytes.Buffer
+		var added, removed int
 
 		// Write file header
-		diffOutput.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", filePath, filePath))
+		fileOutput.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", filePath, filePath))
 		if fileStatus.Worktree == git.Added {
-			diffOutput.WriteString("new file mode 100644\n")
+			fileOutput.WriteString("new file mode 100644\n")
 		} else if fileStatus.Worktree == git.Deleted {
-			diffOutput.WriteString("deleted file mode 100644\n")
+			fileOutput.WriteString("deleted file mode 100644\n")
 		}
-		diffOutput.WriteString("--- a/" + filePath + "\n")
-		diffOutput.WriteString("+++ b/" + filePath + "\n")
+		fileOutput.WriteString("--- a/" + filePath + "\n")
+		fileOutput.WriteString("+++ b/" + filePath + "\n")
 
 		// Generate unified diff
 		baseLines := strings.Split(string(baseContent), "\n")
@@ -95,19 +194,27 @@ func (g *GitWorktree) Diff() *DiffStats {
 		var i, j int
 		for i < len(baseLines) || j < len(currentLines) {
 			if i < len(baseLines) && j < len(currentLines) && baseLines[i] == currentLines[j] {
-				diffOutput.WriteString(" " + baseLines[i] + "\n")
+				fileOutput.WriteString(" " + baseLines[i] + "\n")
 				i++
 				j++
 			} else if i < len(baseLines) {
-				diffOutput.WriteString("-" + baseLines[i] + "\n")
-				stats.Removed++
+				fileOutput.WriteString("-" + baseLines[i] + "\n")
+				removed++
 				i++
 			} else if j < len(currentLines) {
-				diffOutput.WriteString("+" + currentLines[j] + "\n")
-				stats.Added++
+				fileOutput.WriteString("+" + currentLines[j] + "\n")
+				added++
 				j++
 			}
 		}
+
+		stats.TotalAdded += added
+		stats.TotalRemoved += removed
+		if included {
+			diffOutput.Write(fileOutput.Bytes())
+			stats.Added += added
+			stats.Removed += removed
+		}
 	}
 
 	stats.Content = diffOutput.String()
@@ -115,6 +222,81 @@ func (g *GitWorktree) Diff() *DiffStats {
 	return stats
 }
 
+// SnapshotTree writes the current content of the worktree, including untracked files which aren't ignored, to a
+// tree object and returns its hash. The index and branches aren't touched, so nothing is committed. The tree is
+// pinned by a ref until ReleaseSnapshot, so git gc doesn't delete it.
+func (g *GitWorktree) SnapshotTree() (string, error) {
+	// Stage everything in a temporary index so the real one is left alone.
+	dir, err := os.MkdirTemp("", "claudesquad-snapshot-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(dir, "index"))
+
+	var output []byte
+	for _, args := range [][]string{{"read-tree", "HEAD"}, {"add", "-A"}, {"write-tree"}} {
+		cmd := exec.Command("git", append([]string{"-C", g.worktreePath}, args...)...)
+		cmd.Env = env
+		if output, err = cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to snapshot worktree: %s (%w)", output, err)
+		}
+	}
+	tree := strings.TrimSpace(string(output))
+	if _, err := g.runGitCommand(g.repoPath, "update-ref", g.snapshotRef(), tree); err != nil {
+		return "", fmt.Errorf("failed to pin snapshot: %w", err)
+	}
+	return tree, nil
+}
+
+// ReleaseSnapshot deletes the ref pinning the tree written by SnapshotTree. It does nothing if there's none.
+func (g *GitWorktree) ReleaseSnapshot() error {
+	if _, err := g.runGitCommand(g.repoPath, "update-ref", "-d", g.snapshotRef()); err != nil {
+		return fmt.Errorf("failed to release snapshot: %w", err)
+	}
+	return nil
+}
+
+// snapshotRef is the ref pinning the session's snapshot. It's named after the branch, which is unique to the
+// session.
+func (g *GitWorktree) snapshotRef() string {
+	return "refs/claudesquad/baseline/" + g.branchName
+}
+
+// ChangedFiles returns the files changed in the worktree since the base commit, one per entry as the change type
+// (A, M or D), a tab and the path. Untracked files are listed as added.
+func (g *GitWorktree) ChangedFiles() ([]string, error) {
+	if g.baseCommitSHA == "" {
+		return nil, fmt.Errorf("base commit SHA not set")
+	}
+	output, err := g.runGitCommand(g.worktreePath, "diff", "--name-status", "--no-renames", g.baseCommitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+	untracked, err := g.runGitCommand(g.worktreePath, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	for _, path := range strings.Split(untracked, "\n") {
+		if path != "" {
+			files = append(files, "A\t"+path)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		_, a, _ := strings.Cut(files[i], "\t")
+		_, b, _ := strings.Cut(files[j], "\t")
+		return a < b
+	})
+	return files, nil
+}
+
 func sortStatuses(status git.Status) ([]*git.FileStatus, []string) {
 	paths := make([]string, 0, len(status))
 	for path := range status {
@@ -129,8 +311,9 @@ func sortStatuses(status git.Status) ([]*git.FileStatus, []string) {
 	return statuses, paths
 }
 
-// getGitObjects initializes the diff operation by setting up the repository, worktree and base tree
-func (g *GitWorktree) prepareGitObjectsForDiff() (*git.Worktree, *object.Tree, *DiffStats) {
+// getGitObjects initializes the diff operation by setting up the repository, worktree and base tree. The base tree
+// is the baseline tree if it's set, or the tree of the base commit.
+func (g *GitWorktree) prepareGitObjectsForDiff(baseline string) (*git.Worktree, *object.Tree, *DiffStats) {
 	stats := &DiffStats{}
 
 	if g.baseCommitSHA == "" {
@@ -151,6 +334,19 @@ func (g *GitWorktree) prepareGitObjectsForDiff() (*git.Worktree, *object.Tree, *
 		stats.Error = fmt.Errorf("failed to get base commit: %w", err)
 		return nil, nil, stats
 	}
+	// The worktree's objects are stored in the main repository, so look up the baseline here too.
+	var baselineTree *object.Tree
+	if baseline != "" {
+		baselineTree, err = repo.TreeObject(plumbing.NewHash(baseline))
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			stats.Error = fmt.Errorf("%w: %s", ErrBaselineMissing, baseline)
+			return nil, nil, stats
+		}
+		if err != nil {
+			stats.Error = fmt.Errorf("failed to get baseline tree: %w", err)
+			return nil, nil, stats
+		}
+	}
 
 	repo, err = git.PlainOpen(g.worktreePath)
 	if err != nil {
@@ -171,6 +367,9 @@ func (g *GitWorktree) prepareGitObjectsForDiff() (*git.Worktree, *object.Tree, *
 		stats.Error = fmt.Errorf("failed to get base tree: %w", err)
 		return nil, nil, stats
 	}
+	if baselineTree != nil {
+		baseTree = baselineTree
+	}
 
 	return worktree, baseTree, stats
 }
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotTree(t *testing.T) {
+	repo, worktree := newMergeTestWorktree(t, "")
+	path := worktree.GetWorktreePath()
+	commitFile(t, path, "seen.txt", "seen\n")
+	// Untracked files are only in the snapshot, which makes its tree unreachable from any commit.
+	writeFile(t, path, "untracked.txt", "untracked\n")
+
+	tree, err := worktree.SnapshotTree()
+	if err != nil {
+		t.Fatalf("SnapshotTree() returned error: %v", err)
+	}
+	runGit(t, repo, "gc", "-q", "--prune=now")
+	if got := runGit(t, repo, "cat-file", "-t", tree); got != "tree" {
+		t.Fatalf("snapshot is a %q after git gc, want it kept", got)
+	}
+
+	writeFile(t, path, "unseen.txt", "unseen\n")
+	stats := worktree.DiffSince(tree, nil)
+	if stats.Error != nil {
+		t.Fatalf("DiffSince() returned error: %v", stats.Error)
+	}
+	if !strings.Contains(stats.Content, "unseen.txt") || strings.Contains(stats.Content, "untracked.txt") {
+		t.Errorf("DiffSince() = %q, want only the change made after the snapshot", stats.Content)
+	}
+
+	if err := worktree.ReleaseSnapshot(); err != nil {
+		t.Fatalf("ReleaseSnapshot() returned error: %v", err)
+	}
+	runGit(t, repo, "gc", "-q", "--prune=now")
+	if stats := worktree.DiffSince(tree, nil); !errors.Is(stats.Error, ErrBaselineMissing) {
+		t.Errorf("DiffSince() with a collected baseline returned error %v, want %v", stats.Error, ErrBaselineMissing)
+	}
+	if err := worktree.ReleaseSnapshot(); err != nil {
+		t.Errorf("ReleaseSnapshot() without a snapshot returned error: %v", err)
+	}
+}
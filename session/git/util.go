@@ -1,7 +1,9 @@
 package git
 
 import (
+	"claude-squad/homedir"
 	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -47,3 +49,48 @@ func checkGHCLI() error {
 
 	return nil
 }
+
+// ListBranches returns the local branches of the repository at repoPath, with the current branch first. current
+// is empty if HEAD is detached.
+func ListBranches(repoPath string) (branches []string, current string, err error) {
+	output, err := exec.Command("git", "-C", repoPath, "for-each-ref", "--format=%(refname:short)", "refs/heads").Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	// symbolic-ref fails when HEAD is detached, which just means there's no current branch.
+	if output, err := exec.Command("git", "-C", repoPath, "symbolic-ref", "--short", "HEAD").Output(); err == nil {
+		current = strings.TrimSpace(string(output))
+	}
+
+	if current != "" {
+		branches = append(branches, current)
+	}
+	for _, branch := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if branch != "" && branch != current {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, current, nil
+}
+
+// FindRepoRoot returns the root of the git repository containing path. A leading "~" is expanded to the
+// home directory. Returns an error if path is not inside a git repository.
+func FindRepoRoot(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("invalid path %s: %w", path, err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository", path)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -2,13 +2,30 @@ package git
 
 import (
 	"claude-squad/config"
+	"claude-squad/homedir"
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 )
 
-func getWorktreeDirectory() (string, error) {
+// worktreeBaseDir is the directory new worktrees are created in. If empty, they're created in the config directory.
+var worktreeBaseDir string
+
+// SetWorktreeBaseDir sets the directory new worktrees are created in. A leading "~" is expanded to the home
+// directory. An empty dir restores the default.
+func SetWorktreeBaseDir(dir string) error {
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return err
+	}
+	worktreeBaseDir = dir
+	return nil
+}
+
+// defaultWorktreeDirectory returns the directory worktrees are created in when no base directory is configured.
+func defaultWorktreeDirectory() (string, error) {
 	configDir, err := config.GetConfigDir()
 	if err != nil {
 		return "", err
@@ -17,6 +34,33 @@ func getWorktreeDirectory() (string, error) {
 	return filepath.Join(configDir, "worktrees"), nil
 }
 
+func getWorktreeDirectory() (string, error) {
+	if worktreeBaseDir == "" {
+		return defaultWorktreeDirectory()
+	}
+	if err := checkWritableDir(worktreeBaseDir); err != nil {
+		return "", fmt.Errorf("invalid worktree base directory: %w", err)
+	}
+	return worktreeBaseDir, nil
+}
+
+// checkWritableDir returns an error if dir is not an existing directory we can create files in.
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".claudesquad-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
 // GitWorktree manages git worktree operations for a session
 type GitWorktree struct {
 	// Path to the repository
@@ -29,18 +73,33 @@ type GitWorktree struct {
 	branchName string
 	// Base commit hash for the worktree
 	baseCommitSHA string
+	// baseBranch is the branch new worktrees start from. If empty, they start from HEAD, and it's set to the
+	// branch checked out in the repository then, if any. Merges go into it.
+	baseBranch string
 }
 
-func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string) *GitWorktree {
+func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string, baseBranch string) *GitWorktree {
 	return &GitWorktree{
 		repoPath:      repoPath,
 		worktreePath:  worktreePath,
 		sessionName:   sessionName,
 		branchName:    branchName,
 		baseCommitSHA: baseCommitSHA,
+		baseBranch:    baseBranch,
 	}
 }
 
+// NewWorktreePath returns a new, unique path for the worktree of a session in the worktree directory.
+func NewWorktreePath(sessionName string) (string, error) {
+	worktreeDir, err := getWorktreeDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	worktreePath := filepath.Join(worktreeDir, sanitizeBranchName(sessionName))
+	return worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano()), nil
+}
+
 // NewGitWorktree creates a new GitWorktree instance
 func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
 	sanitizedName := sanitizeBranchName(sessionName)
@@ -54,14 +113,11 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 		absPath = repoPath
 	}
 
-	worktreeDir, err := getWorktreeDirectory()
+	worktreePath, err := NewWorktreePath(sessionName)
 	if err != nil {
 		return nil, "", err
 	}
 
-	worktreePath := filepath.Join(worktreeDir, sanitizedName)
-	worktreePath = worktreePath + "_" + fmt.Sprintf("%x", time.Now().UnixNano())
-
 	return &GitWorktree{
 		repoPath:     absPath,
 		sessionName:  sessionName,
@@ -70,6 +126,11 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 	}, branchName, nil
 }
 
+// SetBaseBranch sets the branch a new worktree starts from. An empty branch starts it from the repository's HEAD.
+func (g *GitWorktree) SetBaseBranch(branch string) {
+	g.baseBranch = branch
+}
+
 // GetWorktreePath returns the path to the worktree
 func (g *GitWorktree) GetWorktreePath() string {
 	return g.worktreePath
@@ -94,3 +155,8 @@ func (g *GitWorktree) GetRepoName() string {
 func (g *GitWorktree) GetBaseCommitSHA() string {
 	return g.baseCommitSHA
 }
+
+// GetBaseBranch returns the branch the worktree was started from, or "" if it's not known.
+func (g *GitWorktree) GetBaseBranch() string {
+	return g.baseBranch
+}
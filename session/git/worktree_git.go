@@ -3,10 +3,41 @@ package git
 import (
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
+// commitAuthorName and commitAuthorEmail override the git identity used for commits made by claude-squad. Empty
+// values keep the identity configured in git.
+var commitAuthorName, commitAuthorEmail string
+
+// SetCommitAuthor sets the name and email commits made by claude-squad are attributed to. An empty value keeps
+// the one configured in git.
+func SetCommitAuthor(name, email string) {
+	commitAuthorName = name
+	commitAuthorEmail = email
+}
+
+// authorArgs returns the git arguments which make commits as the configured commit author.
+func authorArgs() []string {
+	var args []string
+	if commitAuthorName != "" {
+		args = append(args, "-c", "user.name="+commitAuthorName)
+	}
+	if commitAuthorEmail != "" {
+		args = append(args, "-c", "user.email="+commitAuthorEmail)
+	}
+	return args
+}
+
+// commitArgs returns the git arguments which commit the staged changes with the message, as the configured
+// commit author.
+func commitArgs(commitMessage string) []string {
+	return append(authorArgs(), "commit", "-m", commitMessage)
+}
+
 // runGitCommand executes a git command and returns any error
 func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error) {
 	baseArgs := []string{"-C", path}
@@ -20,6 +51,26 @@ func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error)
 	return string(output), nil
 }
 
+// CommitChanges stages and commits all changes in the worktree without pushing them. Returns false if there was
+// nothing to commit.
+func (g *GitWorktree) CommitChanges(commitMessage string) (bool, error) {
+	isDirty, err := g.IsDirty()
+	if err != nil {
+		return false, fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if !isDirty {
+		return false, nil
+	}
+
+	if _, err := g.runGitCommand(g.worktreePath, "add", "."); err != nil {
+		return false, fmt.Errorf("failed to stage changes: %w", err)
+	}
+	if _, err := g.runGitCommand(g.worktreePath, commitArgs(commitMessage)...); err != nil {
+		return false, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return true, nil
+}
+
 // PushChanges commits and pushes changes in the worktree to the remote branch
 func (g *GitWorktree) PushChanges(commitMessage string) error {
 	if err := checkGHCLI(); err != nil {
@@ -40,7 +91,7 @@ func (g *GitWorktree) PushChanges(commitMessage string) error {
 		}
 
 		// Create commit
-		if _, err := g.runGitCommand(g.worktreePath, "commit", "-m", commitMessage); err != nil {
+		if _, err := g.runGitCommand(g.worktreePath, commitArgs(commitMessage)...); err != nil {
 			log.ErrorLog.Print(err)
 			return fmt.Errorf("failed to commit changes: %w", err)
 		}
@@ -70,6 +121,25 @@ func (g *GitWorktree) PushChanges(commitMessage string) error {
 	return nil
 }
 
+// CreatePullRequest opens a pull request for the pushed branch using the GitHub CLI. Returns the URL of the
+// pull request.
+func (g *GitWorktree) CreatePullRequest(title, body string) (string, error) {
+	if err := checkGHCLI(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("gh", "pr", "create", "--head", g.branchName, "--title", title, "--body", body)
+	cmd.Dir = g.worktreePath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.ErrorLog.Print(err)
+		return "", fmt.Errorf("failed to create pull request: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+	// gh prints the URL of the pull request last.
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return lines[len(lines)-1], nil
+}
+
 // IsDirty checks if the worktree has uncommitted changes
 func (g *GitWorktree) IsDirty() (bool, error) {
 	output, err := g.runGitCommand(g.worktreePath, "status", "--porcelain")
@@ -79,6 +149,55 @@ func (g *GitWorktree) IsDirty() (bool, error) {
 	return len(output) > 0, nil
 }
 
+// CurrentHead returns the name of the branch checked out in the worktree, or the short hash of the commit if its
+// HEAD is detached. It reads the HEAD file rather than running git, since it's called for every instance on each
+// metadata tick.
+func (g *GitWorktree) CurrentHead() (string, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(content))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/"), nil
+	}
+	if len(head) > shortHashLength {
+		head = head[:shortHashLength]
+	}
+	return head, nil
+}
+
+// shortHashLength is the length commit hashes are shortened to, like git does in small repositories.
+const shortHashLength = 7
+
+// gitDir returns the git directory of the worktree. In a linked worktree, .git is a file with its path.
+func (g *GitWorktree) gitDir() (string, error) {
+	dotGit := filepath.Join(g.worktreePath, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to find worktree git directory: %w", err)
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read worktree git directory: %w", err)
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("unexpected content in %s: %q", dotGit, content)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(g.worktreePath, dir)
+	}
+	return dir, nil
+}
+
 // IsBranchCheckedOut checks if the instance branch is currently checked out
 func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
 	output, err := g.runGitCommand(g.repoPath, "branch", "--show-current")
@@ -87,3 +206,135 @@ func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
 	}
 	return strings.TrimSpace(string(output)) == g.branchName, nil
 }
+
+// MergeTarget returns the branch MergeIntoBase merges into: the branch the worktree was started from, or the
+// branch checked out in the repository if that wasn't recorded.
+func (g *GitWorktree) MergeTarget() (string, error) {
+	if g.baseBranch != "" {
+		return g.baseBranch, nil
+	}
+	output, err := g.runGitCommand(g.repoPath, "branch", "--show-current")
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	base := strings.TrimSpace(output)
+	if base == "" {
+		return "", fmt.Errorf("no base branch was recorded and no branch is checked out in %s to merge into", g.repoPath)
+	}
+	return base, nil
+}
+
+// MergeIntoBase merges the worktree's branch into its base branch, see MergeTarget. With rebase, the branch is
+// rebased onto the base branch in the worktree instead, and the base branch is fast-forwarded to it. Returns the
+// name of the base branch. Uncommitted changes in the worktree aren't merged. If the base branch isn't checked out
+// anywhere, it's merged in a temporary worktree. On conflicts, the merge or rebase is left in progress to be
+// resolved by hand, and the error says where.
+func (g *GitWorktree) MergeIntoBase(rebase bool) (string, error) {
+	base, err := g.MergeTarget()
+	if err != nil {
+		return "", err
+	}
+	if base == g.branchName {
+		return "", fmt.Errorf("branch %s is its own base, so there's nothing to merge it into", base)
+	}
+	checkout, err := g.checkoutOf(base)
+	if err != nil {
+		return "", err
+	}
+	if checkout != "" {
+		// Merging into a checkout with uncommitted changes could mix them into the merge.
+		status, err := g.runGitCommand(checkout, "status", "--porcelain", "--untracked-files=no")
+		if err != nil {
+			return "", fmt.Errorf("failed to check the status of %s: %w", checkout, err)
+		}
+		if status != "" {
+			return "", fmt.Errorf("%s has uncommitted changes, commit or stash them before merging", checkout)
+		}
+	}
+
+	if rebase {
+		if _, err := g.runGitCommand(g.worktreePath, append(authorArgs(), "rebase", base)...); err != nil {
+			if files := g.conflictedFiles(g.worktreePath); len(files) > 0 {
+				return base, fmt.Errorf("rebasing %s onto %s stopped on conflicts in %s. Resolve them in %s and run "+
+					"git rebase --continue, or git rebase --abort to undo it", g.branchName, base,
+					strings.Join(files, ", "), g.worktreePath)
+			}
+			return base, fmt.Errorf("failed to rebase %s onto %s: %w", g.branchName, base, err)
+		}
+		if checkout != "" {
+			if _, err := g.runGitCommand(checkout, "merge", "--ff-only", g.branchName); err != nil {
+				return base, fmt.Errorf("failed to fast-forward %s to %s: %w", base, g.branchName, err)
+			}
+			return base, nil
+		}
+		// Without a checkout, move the branch directly. Passing the old commit makes sure it didn't move meanwhile.
+		oldBase, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "refs/heads/"+base)
+		if err != nil {
+			return base, fmt.Errorf("failed to get the commit of %s: %w", base, err)
+		}
+		if _, err := g.runGitCommand(g.repoPath, "update-ref", "refs/heads/"+base, g.branchName,
+			strings.TrimSpace(oldBase)); err != nil {
+			return base, fmt.Errorf("failed to fast-forward %s to %s: %w", base, g.branchName, err)
+		}
+		return base, nil
+	}
+
+	temporary := checkout == ""
+	if temporary {
+		if checkout, err = os.MkdirTemp("", "claudesquad-merge-"); err != nil {
+			return base, fmt.Errorf("failed to create a directory to merge in: %w", err)
+		}
+		if _, err := g.runGitCommand(g.repoPath, "worktree", "add", checkout, base); err != nil {
+			_ = os.RemoveAll(checkout)
+			return base, fmt.Errorf("failed to check out %s to merge into: %w", base, err)
+		}
+	}
+	if _, err := g.runGitCommand(checkout, append(authorArgs(), "merge", "--no-ff", "--no-edit", g.branchName)...); err != nil {
+		if files := g.conflictedFiles(checkout); len(files) > 0 {
+			// The temporary worktree is kept, since the merge has to be finished there.
+			return base, fmt.Errorf("merging %s into %s stopped on conflicts in %s. Resolve them in %s and run "+
+				"git commit, or git merge --abort to undo it", g.branchName, base, strings.Join(files, ", "), checkout)
+		}
+		g.removeTemporaryWorktree(checkout, temporary)
+		return base, fmt.Errorf("failed to merge %s into %s: %w", g.branchName, base, err)
+	}
+	g.removeTemporaryWorktree(checkout, temporary)
+	return base, nil
+}
+
+// checkoutOf returns the path of the worktree of the repository which has branch checked out, or "" if none has.
+func (g *GitWorktree) checkoutOf(branch string) (string, error) {
+	output, err := g.runGitCommand(g.repoPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	var path string
+	for _, line := range strings.Split(output, "\n") {
+		if p, ok := strings.CutPrefix(line, "worktree "); ok {
+			path = p
+		} else if line == "branch refs/heads/"+branch {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// removeTemporaryWorktree removes the worktree MergeIntoBase created at path, if it created one.
+func (g *GitWorktree) removeTemporaryWorktree(path string, temporary bool) {
+	if !temporary {
+		return
+	}
+	if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", path); err != nil {
+		log.ErrorLog.Printf("failed to remove temporary worktree %s: %v", path, err)
+	}
+}
+
+// conflictedFiles returns the files with unresolved conflicts in the checkout at path.
+func (g *GitWorktree) conflictedFiles(path string) []string {
+	output, err := g.runGitCommand(path, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		log.ErrorLog.Printf("failed to list conflicted files: %v", err)
+		return nil
+	}
+	return strings.Fields(output)
+}
@@ -0,0 +1,208 @@
+package git
+
+import (
+	"claude-squad/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	code := m.Run()
+	log.Close()
+	os.Exit(code)
+}
+
+// runGit runs git in dir and returns its trimmed output, failing the test if it fails.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s (%v)", strings.Join(args, " "), output, err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// writeFile writes content to name in dir.
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitFile writes content to name in dir and commits it.
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	writeFile(t, dir, name, content)
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-q", "-m", "change "+name)
+}
+
+// newMergeTestWorktree creates a repository on main with a develop branch, and a session worktree started from
+// base. An empty base starts it from main, which is checked out.
+func newMergeTestWorktree(t *testing.T, base string) (repo string, worktree *GitWorktree) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	origBaseDir := worktreeBaseDir
+	t.Cleanup(func() { worktreeBaseDir = origBaseDir })
+	if err := SetWorktreeBaseDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	repo = t.TempDir()
+	runGit(t, repo, "init", "-q")
+	runGit(t, repo, "symbolic-ref", "HEAD", "refs/heads/main")
+	commitFile(t, repo, "shared.txt", "initial\n")
+	runGit(t, repo, "branch", "develop")
+
+	worktree, _, err := NewGitWorktree(repo, "merge test")
+	if err != nil {
+		t.Fatalf("NewGitWorktree() returned error: %v", err)
+	}
+	worktree.SetBaseBranch(base)
+	if err := worktree.Setup(); err != nil {
+		t.Fatalf("Setup() returned error: %v", err)
+	}
+	return repo, worktree
+}
+
+func TestMergeIntoBase(t *testing.T) {
+	t.Run("merge into the checked out base", func(t *testing.T) {
+		repo, worktree := newMergeTestWorktree(t, "")
+		if got := worktree.GetBaseBranch(); got != "main" {
+			t.Errorf("GetBaseBranch() = %q, want the branch checked out when it was created, main", got)
+		}
+		commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+
+		base, err := worktree.MergeIntoBase(false)
+		if err != nil {
+			t.Fatalf("MergeIntoBase() returned error: %v", err)
+		}
+		if base != "main" {
+			t.Errorf("MergeIntoBase() merged into %q, want main", base)
+		}
+		if got := runGit(t, repo, "show", "main:feature.txt"); got != "feature" {
+			t.Errorf("main has feature.txt = %q, want the session's change", got)
+		}
+		if merges := runGit(t, repo, "rev-list", "--merges", "main"); merges == "" {
+			t.Error("main has no merge commit")
+		}
+	})
+
+	t.Run("merge into a base which isn't checked out", func(t *testing.T) {
+		repo, worktree := newMergeTestWorktree(t, "develop")
+		commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+		// The user switched the repository since, which mustn't change where the session is merged.
+		runGit(t, repo, "checkout", "-q", "-b", "other")
+
+		base, err := worktree.MergeIntoBase(false)
+		if err != nil {
+			t.Fatalf("MergeIntoBase() returned error: %v", err)
+		}
+		if base != "develop" {
+			t.Errorf("MergeIntoBase() merged into %q, want the session's base, develop", base)
+		}
+		if got := runGit(t, repo, "show", "develop:feature.txt"); got != "feature" {
+			t.Errorf("develop has feature.txt = %q, want the session's change", got)
+		}
+		if got := runGit(t, repo, "branch", "--show-current"); got != "other" {
+			t.Errorf("the repository has %s checked out after merging, want other", got)
+		}
+		if worktrees := strings.Count(runGit(t, repo, "worktree", "list", "--porcelain"), "worktree "); worktrees != 2 {
+			t.Errorf("there are %d worktrees after merging, want the temporary one removed", worktrees)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		repo, worktree := newMergeTestWorktree(t, "")
+		commitFile(t, worktree.GetWorktreePath(), "shared.txt", "session\n")
+		commitFile(t, repo, "shared.txt", "main\n")
+
+		_, err := worktree.MergeIntoBase(false)
+		if err == nil {
+			t.Fatal("MergeIntoBase() succeeded, want a conflict")
+		}
+		if !strings.Contains(err.Error(), "conflicts in shared.txt") || !strings.Contains(err.Error(), repo) {
+			t.Errorf("MergeIntoBase() error = %v, want it to name the conflicted file and where to resolve it", err)
+		}
+		// The merge is left for the user to finish.
+		if _, err := os.Stat(filepath.Join(runGit(t, repo, "rev-parse", "--absolute-git-dir"), "MERGE_HEAD")); err != nil {
+			t.Errorf("no merge is in progress after the conflict: %v", err)
+		}
+	})
+
+	t.Run("rebase", func(t *testing.T) {
+		repo, worktree := newMergeTestWorktree(t, "")
+		commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+		commitFile(t, repo, "other.txt", "other\n")
+
+		base, err := worktree.MergeIntoBase(true)
+		if err != nil {
+			t.Fatalf("MergeIntoBase() returned error: %v", err)
+		}
+		if base != "main" {
+			t.Errorf("MergeIntoBase() rebased onto %q, want main", base)
+		}
+		if main, branch := runGit(t, repo, "rev-parse", "main"), runGit(t, repo, "rev-parse", worktree.GetBranchName()); main != branch {
+			t.Errorf("main is at %s, want it fast-forwarded to the session's branch at %s", main, branch)
+		}
+		if merges := runGit(t, repo, "rev-list", "--merges", "main"); merges != "" {
+			t.Errorf("main has merge commits %s after rebasing, want a linear history", merges)
+		}
+		if got := runGit(t, repo, "show", "main:other.txt"); got != "other" {
+			t.Errorf("main lost its own commit while rebasing, other.txt = %q", got)
+		}
+	})
+
+	t.Run("rebase onto a base which isn't checked out", func(t *testing.T) {
+		repo, worktree := newMergeTestWorktree(t, "develop")
+		commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+
+		if _, err := worktree.MergeIntoBase(true); err != nil {
+			t.Fatalf("MergeIntoBase() returned error: %v", err)
+		}
+		if develop, branch := runGit(t, repo, "rev-parse", "develop"), runGit(t, repo, "rev-parse", worktree.GetBranchName()); develop != branch {
+			t.Errorf("develop is at %s, want it fast-forwarded to the session's branch at %s", develop, branch)
+		}
+		if got := runGit(t, repo, "show", "main:shared.txt"); got != "initial" {
+			t.Errorf("main changed to %q, want it left alone", got)
+		}
+	})
+}
+
+func TestCurrentHead(t *testing.T) {
+	repo, worktree := newMergeTestWorktree(t, "")
+	path := worktree.GetWorktreePath()
+	if got, err := worktree.CurrentHead(); err != nil || got != worktree.GetBranchName() {
+		t.Errorf("CurrentHead() = %q, %v, want the session's branch %q", got, err, worktree.GetBranchName())
+	}
+
+	runGit(t, path, "checkout", "-q", "--detach")
+	want := runGit(t, path, "rev-parse", "--short", "HEAD")
+	if got, err := worktree.CurrentHead(); err != nil || got != want {
+		t.Errorf("CurrentHead() with a detached HEAD = %q, %v, want %q", got, err, want)
+	}
+
+	// The repository itself has a .git directory rather than a file.
+	main, _, err := NewGitWorktree(repo, "main")
+	if err != nil {
+		t.Fatalf("NewGitWorktree() returned error: %v", err)
+	}
+	main.worktreePath = repo
+	if got, err := main.CurrentHead(); err != nil || got != "main" {
+		t.Errorf("CurrentHead() in the repository = %q, %v, want main", got, err)
+	}
+}
@@ -49,7 +49,7 @@ func (g *GitWorktree) SetupFromExistingBranch() error {
 	return nil
 }
 
-// SetupNewWorktree creates a new worktree from HEAD
+// SetupNewWorktree creates a new worktree from the base branch, or HEAD if it's not set
 func (g *GitWorktree) SetupNewWorktree() error {
 	// Ensure worktrees directory exists
 	worktreesDir := filepath.Join(g.repoPath, "worktrees")
@@ -71,17 +71,23 @@ func (g *GitWorktree) SetupNewWorktree() error {
 		return fmt.Errorf("failed to cleanup existing branch: %w", err)
 	}
 
-	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	base := "HEAD"
+	if g.baseBranch != "" {
+		base = g.baseBranch
+	} else if output, err := g.runGitCommand(g.repoPath, "branch", "--show-current"); err == nil {
+		// Remember the branch HEAD is on, so merges go back into it even if the repository is switched since.
+		g.baseBranch = strings.TrimSpace(output)
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", base+"^{commit}")
 	if err != nil {
-		return fmt.Errorf("failed to get HEAD commit hash: %w", err)
+		return fmt.Errorf("failed to get %s commit hash: %w", base, err)
 	}
 	headCommit := strings.TrimSpace(string(output))
 	g.baseCommitSHA = headCommit
 
-	// Create a new worktree from the HEAD commit
+	// Create a new worktree from the base commit
 	// Otherwise, we'll inherit uncommitted changes from the previous worktree.
 	// This way, we can start the worktree with a clean slate.
-	// TODO: we might want to give an option to use main/master instead of the current branch.
 	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
 		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
 	}
@@ -122,6 +128,10 @@ func (g *GitWorktree) Cleanup() error {
 		errs = append(errs, fmt.Errorf("error checking branch %s existence: %w", g.branchName, err))
 	}
 
+	if err := g.ReleaseSnapshot(); err != nil {
+		errs = append(errs, err)
+	}
+
 	// Prune the worktree to clean up any remaining references
 	if err := g.Prune(); err != nil {
 		errs = append(errs, err)
@@ -154,7 +164,7 @@ func (g *GitWorktree) Prune() error {
 
 // CleanupWorktrees removes all worktrees and their associated branches
 func CleanupWorktrees() error {
-	worktreesDir, err := getWorktreeDirectory()
+	worktreesDir, err := defaultWorktreeDirectory()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree directory: %w", err)
 	}
@@ -210,6 +220,19 @@ func CleanupWorktrees() error {
 		}
 	}
 
+	// The configured base directory may hold other files, so only remove the worktrees git knows about there.
+	if worktreeBaseDir != "" {
+		for path, branch := range worktreeBranches {
+			if filepath.Dir(path) != filepath.Clean(worktreeBaseDir) {
+				continue
+			}
+			if err := exec.Command("git", "branch", "-D", branch).Run(); err != nil {
+				log.ErrorLog.Printf("failed to delete branch %s: %v", branch, err)
+			}
+			os.RemoveAll(path)
+		}
+	}
+
 	// You have to prune the cleaned up worktrees.
 	cmd = exec.Command("git", "worktree", "prune")
 	_, err = cmd.Output()
@@ -6,16 +6,90 @@ import (
 	"claude-squad/session/tmux"
 	"path/filepath"
 
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/x/ansi"
 )
 
 type Status int
 
+// maxPromptHistory is the number of prompts kept in an instance's prompt history.
+const maxPromptHistory = 20
+
+// defaultMaxTitleLength is the default of MaxTitleLength.
+const defaultMaxTitleLength = 32
+
+// MaxTitleLength is the maximum length of an instance title.
+var MaxTitleLength = defaultMaxTitleLength
+
+// SetMaxTitleLength sets MaxTitleLength. Non-positive values restore the default.
+func SetMaxTitleLength(length int) {
+	if length <= 0 {
+		length = defaultMaxTitleLength
+	}
+	MaxTitleLength = length
+}
+
+// autoYesDenyPatterns are the regular expressions which stop autoyes from answering a prompt if the pane matches
+// them. Change them with SetAutoYesDenyPatterns.
+var autoYesDenyPatterns []*regexp.Regexp
+
+// SetAutoYesDenyPatterns sets the regular expressions which stop autoyes from answering a prompt when the pane
+// content matches any of them, ex. "rm -rf". An empty list lets autoyes answer every prompt.
+func SetAutoYesDenyPatterns(patterns []string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid autoyes deny pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	autoYesDenyPatterns = compiled
+	return nil
+}
+
+// checkTitleLength returns an error if title is longer than MaxTitleLength.
+func checkTitleLength(title string) error {
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return fmt.Errorf("title cannot be longer than %d characters", MaxTitleLength)
+	}
+	return nil
+}
+
+// AutoCommitOnPause makes Pause commit the worktree's changes locally rather than pushing them. A failed commit
+// doesn't stop the pause; the worktree is kept instead so nothing is lost.
+var AutoCommitOnPause bool
+
+// TranscriptDir is the directory the full scrollback of an instance is saved to when it's paused or killed. No
+// transcripts are saved when it's empty.
+var TranscriptDir string
+
+// TranscriptKeepANSI keeps escape sequences like colors in saved transcripts. They're stripped by default, so
+// transcripts read well in any editor.
+var TranscriptKeepANSI bool
+
+// maxPreviewHistoryLines caps PreviewHistoryLines since the preview is captured on every tick.
+const maxPreviewHistoryLines = 5000
+
+// PreviewHistoryLines is how many lines of scrollback above the visible pane Preview captures. 0 captures only
+// the visible pane.
+var PreviewHistoryLines int
+
+// SetPreviewHistoryLines sets PreviewHistoryLines, clamped to between 0 and maxPreviewHistoryLines.
+func SetPreviewHistoryLines(lines int) {
+	PreviewHistoryLines = max(min(lines, maxPreviewHistoryLines), 0)
+}
+
 const (
 	// Running is the status when the instance is running and claude is working.
 	Running Status = iota
@@ -27,6 +101,31 @@ const (
 	Paused
 )
 
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Ready:
+		return "ready"
+	case Loading:
+		return "loading"
+	case Paused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseStatus parses the name of a status, as returned by String.
+func ParseStatus(s string) (Status, error) {
+	for _, status := range []Status{Running, Ready, Loading, Paused} {
+		if strings.EqualFold(s, status.String()) {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status %q (expected running, ready, loading or paused)", s)
+}
+
 // Instance is a running instance of claude code.
 type Instance struct {
 	// Title is the title of the instance.
@@ -45,21 +144,50 @@ type Instance struct {
 	Width int
 	// CreatedAt is the time the instance was created.
 	CreatedAt time.Time
-	// UpdatedAt is the time the instance was last updated.
+	// UpdatedAt is the last time the instance's output changed.
 	UpdatedAt time.Time
 	// AutoYes is true if the instance should automatically press enter when prompted.
 	AutoYes bool
 	// Prompt is the initial prompt to pass to the instance on startup
 	Prompt string
+	// Tags are user defined labels used to organize instances.
+	Tags []string
+	// Description is a free text note about what the instance is working on.
+	Description string
+	// Pinned instances are shown at the top of the list.
+	Pinned bool
+	// PromptHistory are the prompts sent to the instance, oldest first. It's capped at maxPromptHistory.
+	PromptHistory []string
+	// BaseBranch is the branch the instance's worktree starts from when it's first started. If empty, it starts
+	// from the repository's current HEAD.
+	BaseBranch string
+	// DiffFilter is the spec of the glob patterns selecting which files are shown in the diff. Set it with
+	// SetDiffFilter.
+	DiffFilter string
+	// DiffBaseline is a snapshot of the worktree taken when its changes were marked as seen. If it's set, the diff
+	// only shows the changes since then. Set it with MarkDiffSeen.
+	DiffBaseline string
+	// Pushes is the number of times the instance's branch was pushed, with submit or when pausing.
+	Pushes int
+	// runningSince is when the instance was last started or resumed. It's zero while it's paused.
+	runningSince time.Time
 
 	// DiffStats stores the current git diff statistics
 	diffStats *git.DiffStats
+	// diffFilter is the parsed DiffFilter. nil shows every file.
+	diffFilter *git.DiffFilter
+	// head is what's checked out in the worktree as of the last UpdateHead, which can differ from Branch if it was
+	// switched from inside the session.
+	head string
 
 	// The below fields are initialized upon calling Start().
 
 	started bool
 	// tmuxSession is the tmux session for the instance.
 	tmuxSession *tmux.TmuxSession
+	// deferred is set if the instance was loaded without restoring its tmux session. It's restored the first
+	// time it's used.
+	deferred bool
 	// gitWorktree is the git worktree for the instance.
 	gitWorktree *git.GitWorktree
 }
@@ -74,9 +202,19 @@ func (i *Instance) ToInstanceData() InstanceData {
 		Height:    i.Height,
 		Width:     i.Width,
 		CreatedAt: i.CreatedAt,
-		UpdatedAt: time.Now(),
+		UpdatedAt: i.UpdatedAt,
 		Program:   i.Program,
 		AutoYes:   i.AutoYes,
+		Prompt:    i.Prompt,
+		Tags:      i.Tags,
+		Pinned:    i.Pinned,
+
+		Description:   i.Description,
+		PromptHistory: i.PromptHistory,
+		DiffFilter:    i.DiffFilter,
+		DiffBaseline:  i.DiffBaseline,
+		Pushes:        i.Pushes,
+		RunningSince:  i.runningSince,
 	}
 
 	// Only include worktree data if gitWorktree is initialized
@@ -87,15 +225,18 @@ func (i *Instance) ToInstanceData() InstanceData {
 			SessionName:   i.Title,
 			BranchName:    i.gitWorktree.GetBranchName(),
 			BaseCommitSHA: i.gitWorktree.GetBaseCommitSHA(),
+			BaseBranch:    i.gitWorktree.GetBaseBranch(),
 		}
 	}
 
 	// Only include diff stats if they exist
 	if i.diffStats != nil {
 		data.DiffStats = DiffStatsData{
-			Added:   i.diffStats.Added,
-			Removed: i.diffStats.Removed,
-			Content: i.diffStats.Content,
+			Added:        i.diffStats.Added,
+			Removed:      i.diffStats.Removed,
+			TotalAdded:   i.diffStats.TotalAdded,
+			TotalRemoved: i.diffStats.TotalRemoved,
+			Content:      i.diffStats.Content,
 		}
 	}
 
@@ -104,6 +245,16 @@ func (i *Instance) ToInstanceData() InstanceData {
 
 // FromInstanceData creates a new Instance from serialized data
 func FromInstanceData(data InstanceData) (*Instance, error) {
+	return fromInstanceData(data, false)
+}
+
+// FromInstanceDataDeferred creates a new Instance from serialized data without restoring its tmux session,
+// which is restored when the instance is first used. This is faster when loading many instances.
+func FromInstanceDataDeferred(data InstanceData) (*Instance, error) {
+	return fromInstanceData(data, true)
+}
+
+func fromInstanceData(data InstanceData, deferRestore bool) (*Instance, error) {
 	instance := &Instance{
 		Title:     data.Title,
 		Path:      data.Path,
@@ -114,23 +265,46 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		CreatedAt: data.CreatedAt,
 		UpdatedAt: data.UpdatedAt,
 		Program:   data.Program,
+		AutoYes:   data.AutoYes,
+		Prompt:    data.Prompt,
+		Tags:      data.Tags,
+		Pinned:    data.Pinned,
+
+		Description:   data.Description,
+		PromptHistory: data.PromptHistory,
+		DiffBaseline:  data.DiffBaseline,
+		Pushes:        data.Pushes,
+		runningSince:  data.RunningSince,
 		gitWorktree: git.NewGitWorktreeFromStorage(
 			data.Worktree.RepoPath,
 			data.Worktree.WorktreePath,
 			data.Worktree.SessionName,
 			data.Worktree.BranchName,
 			data.Worktree.BaseCommitSHA,
+			data.Worktree.BaseBranch,
 		),
 		diffStats: &git.DiffStats{
-			Added:   data.DiffStats.Added,
-			Removed: data.DiffStats.Removed,
-			Content: data.DiffStats.Content,
+			Added:        data.DiffStats.Added,
+			Removed:      data.DiffStats.Removed,
+			TotalAdded:   data.DiffStats.TotalAdded,
+			TotalRemoved: data.DiffStats.TotalRemoved,
+			Content:      data.DiffStats.Content,
 		},
 	}
 
-	if instance.Paused() {
+	// Instances stored before creation times were recorded count from when they were loaded.
+	if instance.CreatedAt.IsZero() {
+		instance.CreatedAt = time.Now()
+	}
+
+	if err := instance.SetDiffFilter(data.DiffFilter); err != nil {
+		log.WarningLog.Printf("ignoring diff filter of %s: %v", instance.Title, err)
+	}
+
+	if instance.Paused() || deferRestore {
 		instance.started = true
 		instance.tmuxSession = tmux.NewTmuxSession(instance.Title, instance.Program)
+		instance.deferred = !instance.Paused()
 	} else {
 		if err := instance.Start(false); err != nil {
 			return nil, err
@@ -155,6 +329,10 @@ type InstanceOptions struct {
 func NewInstance(opts InstanceOptions) (*Instance, error) {
 	t := time.Now()
 
+	if err := checkTitleLength(opts.Title); err != nil {
+		return nil, err
+	}
+
 	// Convert path to absolute
 	absPath, err := filepath.Abs(opts.Path)
 	if err != nil {
@@ -195,10 +373,15 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 	i.tmuxSession = tmuxSession
 
 	if firstTimeSetup {
+		// Check the program before creating anything, so a typo doesn't leave a worktree behind.
+		if err := tmux.ValidateProgram(i.Program); err != nil {
+			return err
+		}
 		gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title)
 		if err != nil {
 			return fmt.Errorf("failed to create git worktree: %w", err)
 		}
+		gitWorktree.SetBaseBranch(i.BaseBranch)
 		i.gitWorktree = gitWorktree
 		i.Branch = branchName
 	}
@@ -237,6 +420,8 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			setupErr = fmt.Errorf("failed to start new session: %w", err)
 			return setupErr
 		}
+		i.runningSince = time.Now()
+		recordStats(func(s *Stats) { s.SessionsCreated++ })
 	}
 
 	i.SetStatus(Running)
@@ -253,9 +438,11 @@ func (i *Instance) Kill() error {
 
 	var errs []error
 
+	i.saveTranscript()
+
 	// Always try to cleanup both resources, even if one fails
-	// Clean up tmux session first since it's using the git worktree
-	if i.tmuxSession != nil {
+	// Clean up tmux session first since it's using the git worktree. Paused instances already closed theirs.
+	if i.tmuxSession != nil && i.Status != Paused {
 		if err := i.tmuxSession.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
 		}
@@ -267,10 +454,66 @@ func (i *Instance) Kill() error {
 			errs = append(errs, fmt.Errorf("failed to cleanup git worktree: %w", err))
 		}
 	}
+	i.recordRuntime()
 
 	return i.combineErrors(errs)
 }
 
+// recordRuntime adds the time since the instance was started or resumed to the total runtime in the stats.
+func (i *Instance) recordRuntime() {
+	if i.runningSince.IsZero() {
+		return
+	}
+	runtime := time.Since(i.runningSince)
+	i.runningSince = time.Time{}
+	recordStats(func(s *Stats) { s.Runtime += runtime })
+}
+
+// KillKeepWorktree closes the instance's tmux session but leaves its worktree and branch in place, so the changes
+// can be reviewed by hand. Returns the path of the worktree. Paused instances have no worktree to keep.
+func (i *Instance) KillKeepWorktree() (string, error) {
+	path, err := i.WorktreePath()
+	if err != nil {
+		return "", err
+	}
+	i.saveTranscript()
+	if err := i.tmuxSession.Close(); err != nil {
+		return "", fmt.Errorf("failed to close tmux session: %w", err)
+	}
+	i.recordRuntime()
+	return path, nil
+}
+
+// saveTranscript writes the full scrollback of the instance's pane to a file in TranscriptDir, named after its
+// title and the time. It does nothing if TranscriptDir is empty. Failures are only logged, so they never stop the
+// instance from being paused or killed.
+func (i *Instance) saveTranscript() {
+	if TranscriptDir == "" || i.Status == Paused || i.tmuxSession == nil {
+		return
+	}
+	content, err := i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+	if err != nil {
+		log.WarningLog.Printf("could not capture transcript of %s: %v", i.Title, err)
+		return
+	}
+	if !TranscriptKeepANSI {
+		content = ansi.Strip(content)
+	}
+
+	if err := os.MkdirAll(TranscriptDir, 0755); err != nil {
+		log.WarningLog.Printf("could not create transcript directory: %v", err)
+		return
+	}
+	fileName := fmt.Sprintf("%s-%s.log",
+		diffFileNameRegex.ReplaceAllString(i.Title, "-"), time.Now().Format("20060102_150405"))
+	path := filepath.Join(TranscriptDir, fileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		log.WarningLog.Printf("could not write transcript of %s: %v", i.Title, err)
+		return
+	}
+	log.InfoLog.Printf("saved transcript of %s to %s", i.Title, path)
+}
+
 // combineErrors combines multiple errors into a single error
 func (i *Instance) combineErrors(errs []error) error {
 	if len(errs) == 0 {
@@ -299,19 +542,59 @@ func (i *Instance) Preview() (string, error) {
 	if !i.started || i.Status == Paused {
 		return "", nil
 	}
+	if PreviewHistoryLines > 0 {
+		return i.tmuxSession.CapturePaneContentWithOptions(fmt.Sprintf("-%d", PreviewHistoryLines), "-")
+	}
 	return i.tmuxSession.CapturePaneContent()
 }
 
+// PreviewFullHistory captures the entire scrollback of the tmux pane, not just the visible part.
+func (i *Instance) PreviewFullHistory() (string, error) {
+	if !i.started || i.Status == Paused {
+		return "", nil
+	}
+	return i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+}
+
 func (i *Instance) HasUpdated() (updated bool, hasPrompt bool) {
 	if !i.started {
 		return false, false
 	}
+	if err := i.RestoreDeferred(); err != nil {
+		log.ErrorLog.Print(err)
+		return false, false
+	}
 	return i.tmuxSession.HasUpdated()
 }
 
-// TapEnter sends an enter key press to the tmux session if AutoYes is enabled.
+// AutoYesDenied returns the autoyes deny pattern which matches the visible pane, if any. Autoyes must leave the
+// prompt shown for the user to answer then.
+func (i *Instance) AutoYesDenied() (string, bool) {
+	if len(autoYesDenyPatterns) == 0 || !i.started || i.Status == Paused {
+		return "", false
+	}
+	content, err := i.tmuxSession.CapturePaneContent()
+	if err != nil {
+		log.ErrorLog.Printf("error capturing pane of %s: %v", i.Title, err)
+		// Don't answer a prompt which can't be checked.
+		return "", true
+	}
+	content = ansi.Strip(content)
+	for _, re := range autoYesDenyPatterns {
+		if re.MatchString(content) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
+
+// TapEnter sends an enter key press to the tmux session to answer a prompt. Callers check that autoyes is on.
 func (i *Instance) TapEnter() {
-	if !i.started || !i.AutoYes {
+	if !i.started {
+		return
+	}
+	if err := i.RestoreDeferred(); err != nil {
+		log.ErrorLog.Print(err)
 		return
 	}
 	if err := i.tmuxSession.TapEnter(); err != nil {
@@ -323,17 +606,52 @@ func (i *Instance) Attach() (chan struct{}, error) {
 	if !i.started {
 		return nil, fmt.Errorf("cannot attach instance that has not been started")
 	}
+	if err := i.RestoreDeferred(); err != nil {
+		return nil, err
+	}
 	return i.tmuxSession.Attach()
 }
 
+// AttachReadOnly attaches to the instance without forwarding any input to it.
+func (i *Instance) AttachReadOnly() (chan struct{}, error) {
+	if !i.started {
+		return nil, fmt.Errorf("cannot attach instance that has not been started")
+	}
+	if err := i.RestoreDeferred(); err != nil {
+		return nil, err
+	}
+	return i.tmuxSession.AttachReadOnly()
+}
+
 func (i *Instance) SetPreviewSize(width, height int) error {
 	if !i.started || i.Status == Paused {
 		return fmt.Errorf("cannot set preview size for instance that has not been started or " +
 			"is paused")
 	}
+	if err := i.RestoreDeferred(); err != nil {
+		return err
+	}
 	return i.tmuxSession.SetDetachedSize(width, height)
 }
 
+// Deferred returns true if the instance's tmux session hasn't been restored yet. See FromInstanceDataDeferred.
+func (i *Instance) Deferred() bool {
+	return i.deferred
+}
+
+// RestoreDeferred restores the tmux session of an instance loaded with FromInstanceDataDeferred. It does nothing
+// if the session was already restored.
+func (i *Instance) RestoreDeferred() error {
+	if !i.deferred {
+		return nil
+	}
+	if err := i.tmuxSession.Restore(); err != nil {
+		return fmt.Errorf("failed to restore existing session: %w", err)
+	}
+	i.deferred = false
+	return nil
+}
+
 // GetGitWorktree returns the git worktree for the instance
 func (i *Instance) GetGitWorktree() (*git.GitWorktree, error) {
 	if !i.started {
@@ -342,6 +660,108 @@ func (i *Instance) GetGitWorktree() (*git.GitWorktree, error) {
 	return i.gitWorktree, nil
 }
 
+// promptTemplateData is the data prompt templates are executed with.
+type promptTemplateData struct {
+	Title string
+	Path  string
+}
+
+// SendPromptTemplate expands tmpl, a text/template which can use {{.Title}} and {{.Path}}, and sends the
+// result to the instance as a prompt.
+func (i *Instance) SendPromptTemplate(tmpl string) error {
+	t, err := template.New("prompt").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid prompt template: %w", err)
+	}
+	var prompt strings.Builder
+	if err := t.Execute(&prompt, promptTemplateData{Title: i.Title, Path: i.Path}); err != nil {
+		return fmt.Errorf("failed to expand prompt template: %w", err)
+	}
+	return i.SendPrompt(prompt.String())
+}
+
+// ParseTags parses comma separated tags, dropping empty and duplicate tags.
+func ParseTags(input string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Restart restarts the program running in the instance without touching its tmux session or worktree. This
+// is useful if the program died or got stuck.
+func (i *Instance) Restart() error {
+	if !i.started {
+		return fmt.Errorf("cannot restart instance that has not been started")
+	}
+	if i.Status == Paused {
+		return fmt.Errorf("cannot restart paused instance")
+	}
+	if err := i.RestoreDeferred(); err != nil {
+		return err
+	}
+	if err := i.tmuxSession.RestartProgram(i.Program); err != nil {
+		return fmt.Errorf("failed to restart program: %w", err)
+	}
+	i.SetStatus(Running)
+	return nil
+}
+
+// WorktreePath returns the path of the instance's git worktree. Paused instances have no worktree.
+func (i *Instance) WorktreePath() (string, error) {
+	if !i.started {
+		return "", fmt.Errorf("cannot get worktree path for instance that has not been started")
+	}
+	if i.Status == Paused {
+		return "", fmt.Errorf("cannot get worktree path for paused instance")
+	}
+	return i.gitWorktree.GetWorktreePath(), nil
+}
+
+// AttachCommand returns the shell command which attaches to the instance's tmux session without claude-squad.
+func (i *Instance) AttachCommand() (string, error) {
+	if !i.started || i.Status == Paused {
+		return "", fmt.Errorf("cannot attach to instance that has not been started or is paused")
+	}
+	return i.tmuxSession.AttachCommand(), nil
+}
+
+// AttachInTerminal attaches to the instance in a new window of the terminal emulator run by terminal.
+func (i *Instance) AttachInTerminal(terminal string) error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot attach to instance that has not been started or is paused")
+	}
+	return i.tmuxSession.AttachInTerminal(terminal)
+}
+
+// MergeTarget returns the branch Merge merges the instance's branch into.
+func (i *Instance) MergeTarget() (string, error) {
+	if !i.started || i.Status == Paused {
+		return "", fmt.Errorf("cannot merge instance that has not been started or is paused")
+	}
+	return i.gitWorktree.MergeTarget()
+}
+
+// Merge commits the instance's changes and merges its branch into its base branch, or rebases it onto the base
+// branch if rebase is set. Returns the name of the base branch.
+func (i *Instance) Merge(rebase bool) (string, error) {
+	if !i.started || i.Status == Paused {
+		return "", fmt.Errorf("cannot merge instance that has not been started or is paused")
+	}
+	commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (merged)", i.Title, time.Now().Format(time.RFC822))
+	if _, err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+		return "", err
+	}
+	return i.gitWorktree.MergeIntoBase(rebase)
+}
+
 func (i *Instance) Started() bool {
 	return i.started
 }
@@ -352,6 +772,28 @@ func (i *Instance) SetTitle(title string) error {
 	if i.started {
 		return fmt.Errorf("cannot change title of a started instance")
 	}
+	if err := checkTitleLength(title); err != nil {
+		return err
+	}
+	i.Title = title
+	return nil
+}
+
+// Rename changes the title of a started instance and renames its tmux session to match. The git
+// worktree is left where it is.
+func (i *Instance) Rename(title string) error {
+	if !i.started {
+		return fmt.Errorf("cannot rename instance that has not been started")
+	}
+	if title == "" {
+		return fmt.Errorf("instance title cannot be empty")
+	}
+	if err := checkTitleLength(title); err != nil {
+		return err
+	}
+	if err := i.tmuxSession.Rename(title); err != nil {
+		return fmt.Errorf("failed to rename tmux session: %w", err)
+	}
 	i.Title = title
 	return nil
 }
@@ -360,8 +802,18 @@ func (i *Instance) Paused() bool {
 	return i.Status == Paused
 }
 
-// Pause stops the tmux session and removes the worktree, preserving the branch
+// Pause stops the tmux session and removes the worktree, preserving the branch. Changes are committed and pushed
+// unless AutoCommitOnPause is set.
 func (i *Instance) Pause() error {
+	return i.pause(!AutoCommitOnPause)
+}
+
+// PauseWithoutPush is like Pause, but changes are only committed locally, whatever AutoCommitOnPause is set to.
+func (i *Instance) PauseWithoutPush() error {
+	return i.pause(false)
+}
+
+func (i *Instance) pause(push bool) error {
 	if !i.started {
 		return fmt.Errorf("cannot pause instance that has not been started")
 	}
@@ -370,15 +822,24 @@ func (i *Instance) Pause() error {
 	}
 
 	var errs []error
+	// commitErr is returned after pausing when auto-committing fails.
+	var commitErr error
 
-	// Check if there are any changes to commit
-	if dirty, err := i.gitWorktree.IsDirty(); err != nil {
+	if !push {
+		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
+		if committed, err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+			commitErr = fmt.Errorf("paused, but failed to commit changes so the worktree was kept: %w", err)
+			log.ErrorLog.Print(commitErr)
+		} else if committed {
+			log.InfoLog.Printf("committed changes of %s before pausing", i.Title)
+		}
+	} else if dirty, err := i.gitWorktree.IsDirty(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to check if worktree is dirty: %w", err))
 		log.ErrorLog.Print(err)
 	} else if dirty {
 		// Commit changes with timestamp
 		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
-		if err := i.gitWorktree.PushChanges(commitMsg); err != nil {
+		if err := i.PushChanges(commitMsg); err != nil {
 			errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
 			log.ErrorLog.Print(err)
 			// Return early if we can't commit changes to avoid corrupted state
@@ -386,6 +847,7 @@ func (i *Instance) Pause() error {
 		}
 	}
 
+	i.saveTranscript()
 	// Close tmux session first since it's using the git worktree
 	if err := i.tmuxSession.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
@@ -393,9 +855,11 @@ func (i *Instance) Pause() error {
 		// Return early if we can't close tmux to avoid corrupted state
 		return i.combineErrors(errs)
 	}
+	// There's nothing to restore anymore.
+	i.deferred = false
 
-	// Check if worktree exists before trying to remove it
-	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err == nil {
+	// Check if worktree exists before trying to remove it. Keep it if the uncommitted changes would be lost.
+	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err == nil && commitErr == nil {
 		// Remove worktree but keep branch
 		if err := i.gitWorktree.Remove(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to remove git worktree: %w", err))
@@ -417,8 +881,9 @@ func (i *Instance) Pause() error {
 	}
 
 	i.SetStatus(Paused)
-	_ = clipboard.WriteAll(i.gitWorktree.GetBranchName())
-	return nil
+	i.recordRuntime()
+	recordStats(func(s *Stats) { s.Pauses++ })
+	return commitErr
 }
 
 // Resume recreates the worktree and restarts the tmux session
@@ -438,10 +903,16 @@ func (i *Instance) Resume() error {
 		return fmt.Errorf("cannot resume: branch is checked out, please switch to a different branch")
 	}
 
-	// Setup git worktree
-	if err := i.gitWorktree.Setup(); err != nil {
-		log.ErrorLog.Print(err)
-		return fmt.Errorf("failed to setup git worktree: %w", err)
+	if err := tmux.ValidateProgram(i.Program); err != nil {
+		return err
+	}
+
+	// Setup git worktree, unless it was kept when pausing because its changes couldn't be committed
+	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); err != nil {
+		if err := i.gitWorktree.Setup(); err != nil {
+			log.ErrorLog.Print(err)
+			return fmt.Errorf("failed to setup git worktree: %w", err)
+		}
 	}
 
 	// Create new tmux session
@@ -456,6 +927,22 @@ func (i *Instance) Resume() error {
 	}
 
 	i.SetStatus(Running)
+	i.runningSince = time.Now()
+	recordStats(func(s *Stats) { s.Resumes++ })
+	return nil
+}
+
+// PushChanges commits any changes in the instance's worktree with commitMsg and pushes its branch. The push is
+// counted in Pushes and the usage stats.
+func (i *Instance) PushChanges(commitMsg string) error {
+	if i.gitWorktree == nil {
+		return fmt.Errorf("cannot push changes of instance that has not been started")
+	}
+	if err := i.gitWorktree.PushChanges(commitMsg); err != nil {
+		return err
+	}
+	i.Pushes++
+	recordStats(func(s *Stats) { s.Pushes++ })
 	return nil
 }
 
@@ -471,7 +958,13 @@ func (i *Instance) UpdateDiffStats() error {
 		return nil
 	}
 
-	stats := i.gitWorktree.Diff()
+	stats := i.gitWorktree.DiffSince(i.DiffBaseline, i.diffFilter)
+	if errors.Is(stats.Error, git.ErrBaselineMissing) {
+		// Sessions marked as seen before baselines were pinned can lose theirs to git gc.
+		log.WarningLog.Printf("showing the full diff of %s: %v", i.Title, stats.Error)
+		i.DiffBaseline = ""
+		stats = i.gitWorktree.Diff(i.diffFilter)
+	}
 	if stats.Error != nil {
 		if strings.Contains(stats.Error.Error(), "base commit SHA not set") {
 			// Worktree is not fully set up yet, not an error
@@ -485,11 +978,151 @@ func (i *Instance) UpdateDiffStats() error {
 	return nil
 }
 
+// UpdateHead reads what's checked out in the instance's worktree, the branch name or the short commit hash if
+// HEAD is detached. Paused instances keep the previous one.
+func (i *Instance) UpdateHead() error {
+	if !i.started || i.Status == Paused {
+		return nil
+	}
+	head, err := i.gitWorktree.CurrentHead()
+	if err != nil {
+		return err
+	}
+	i.head = head
+	return nil
+}
+
+// Head returns what's checked out in the instance's worktree as of the last UpdateHead, or Branch if it
+// hasn't been read yet.
+func (i *Instance) Head() string {
+	if i.head == "" {
+		return i.Branch
+	}
+	return i.head
+}
+
+// ChangedFiles returns the files changed in the instance's worktree which match its diff filter, one per entry as
+// the change type (A, M or D), a tab and the path.
+func (i *Instance) ChangedFiles() ([]string, error) {
+	if !i.started {
+		return nil, fmt.Errorf("cannot list changed files of instance that has not been started")
+	}
+	if i.Status == Paused {
+		return nil, fmt.Errorf("cannot list changed files of a paused instance")
+	}
+
+	files, err := i.gitWorktree.ChangedFiles()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(file string) bool {
+		_, path, _ := strings.Cut(file, "\t")
+		return !i.diffFilter.Matches(path)
+	}), nil
+}
+
+// SetDiffFilter sets the comma separated glob patterns selecting which files are shown in the diff, ex.
+// "*.go,!*_test.go". An empty spec shows every file. The diff is updated on the next UpdateDiffStats.
+func (i *Instance) SetDiffFilter(spec string) error {
+	filter, err := git.ParseDiffFilter(spec)
+	if err != nil {
+		return err
+	}
+	i.DiffFilter = spec
+	i.diffFilter = filter
+	return nil
+}
+
+// MarkDiffSeen snapshots the worktree so the diff only shows the changes made after this.
+func (i *Instance) MarkDiffSeen() error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot mark diff of instance that has not been started or is paused as seen")
+	}
+	tree, err := i.gitWorktree.SnapshotTree()
+	if err != nil {
+		return err
+	}
+	i.DiffBaseline = tree
+	return i.UpdateDiffStats()
+}
+
+// ClearDiffBaseline shows all the changes since the base branch in the diff again.
+func (i *Instance) ClearDiffBaseline() error {
+	i.DiffBaseline = ""
+	if i.gitWorktree != nil {
+		if err := i.gitWorktree.ReleaseSnapshot(); err != nil {
+			return err
+		}
+	}
+	return i.UpdateDiffStats()
+}
+
+// ExportDiff writes the full diff of the instance to a new file in dir and returns the path of the file. If
+// copyToClipboard is true, the diff is also copied to the clipboard.
+func (i *Instance) ExportDiff(dir string, copyToClipboard bool) (string, error) {
+	if !i.started {
+		return "", fmt.Errorf("cannot export diff for instance that has not been started")
+	}
+
+	var content string
+	if i.Status == Paused {
+		// The worktree is removed while paused, so use the last diff we computed.
+		if i.diffStats != nil {
+			content = i.diffStats.Content
+		}
+	} else {
+		// Export every file, not just the ones the filter shows.
+		stats := i.gitWorktree.Diff(nil)
+		if stats.Error != nil {
+			return "", fmt.Errorf("failed to compute diff: %w", stats.Error)
+		}
+		content = stats.Content
+	}
+	if content == "" {
+		return "", fmt.Errorf("no changes to export")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create diff directory: %w", err)
+	}
+	fileName := fmt.Sprintf("%s-%s.diff",
+		diffFileNameRegex.ReplaceAllString(i.Title, "-"), time.Now().Format("20060102_150405"))
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write diff: %w", err)
+	}
+
+	if copyToClipboard {
+		if err := clipboard.WriteAll(content); err != nil {
+			log.WarningLog.Printf("could not copy diff to clipboard: %v", err)
+		}
+	}
+	return path, nil
+}
+
+// diffFileNameRegex matches characters which we don't want in exported diff file names.
+var diffFileNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
 // GetDiffStats returns the current git diff statistics
 func (i *Instance) GetDiffStats() *git.DiffStats {
 	return i.diffStats
 }
 
+// SendKeys writes keys to the program in the instance's tmux session as if they were typed, ex. "\x03" for ctrl+c.
+// Unlike SendPrompt, enter isn't pressed afterwards.
+func (i *Instance) SendKeys(keys string) error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot send keys to instance that has not been started or is paused")
+	}
+	if err := i.RestoreDeferred(); err != nil {
+		return err
+	}
+	if err := i.tmuxSession.SendKeys(keys); err != nil {
+		return fmt.Errorf("error sending keys to tmux session: %w", err)
+	}
+	return nil
+}
+
 // SendPrompt sends a prompt to the tmux session
 func (i *Instance) SendPrompt(prompt string) error {
 	if !i.started {
@@ -498,6 +1131,9 @@ func (i *Instance) SendPrompt(prompt string) error {
 	if i.tmuxSession == nil {
 		return fmt.Errorf("tmux session not initialized")
 	}
+	if err := i.RestoreDeferred(); err != nil {
+		return err
+	}
 	if err := i.tmuxSession.SendKeys(prompt); err != nil {
 		return fmt.Errorf("error sending keys to tmux session: %w", err)
 	}
@@ -508,5 +1144,10 @@ func (i *Instance) SendPrompt(prompt string) error {
 		return fmt.Errorf("error tapping enter: %w", err)
 	}
 
+	recordStats(func(s *Stats) { s.PromptsSent++ })
+	i.PromptHistory = append(i.PromptHistory, prompt)
+	if len(i.PromptHistory) > maxPromptHistory {
+		i.PromptHistory = i.PromptHistory[len(i.PromptHistory)-maxPromptHistory:]
+	}
 	return nil
 }
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Stats are usage counters kept in stats.json in the config directory. They're only stored locally and never
+// sent anywhere.
+type Stats struct {
+	// Since is when the first stat was recorded.
+	Since           time.Time
+	SessionsCreated int
+	PromptsSent     int
+	Pushes          int
+	Pauses          int
+	Resumes         int
+	// Runtime is how long sessions ran in total, from being started or resumed until they were paused or killed.
+	// Sessions which are still running aren't included.
+	Runtime time.Duration
+}
+
+// statsMu serializes updates to the stats file, since instances are started in the background. Other processes,
+// ex. the daemon, are kept out by lockStats.
+var statsMu sync.Mutex
+
+const (
+	// statsLockTimeout is how long an update waits for another process to release the stats lock.
+	statsLockTimeout = 2 * time.Second
+	// staleStatsLock is the age after which a stats lock is assumed to be left by a process which died.
+	staleStatsLock = 10 * time.Second
+)
+
+func statsPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "stats.json"), nil
+}
+
+// LoadStats loads the usage stats. Returns the zero stats if none were recorded yet.
+func LoadStats() (Stats, error) {
+	var stats Stats
+	path, err := statsPath()
+	if err != nil {
+		return stats, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("failed to read stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return Stats{}, fmt.Errorf("failed to parse stats: %w", err)
+	}
+	return stats, nil
+}
+
+// recordStats applies update to the stored stats. Failures are only logged, so stats never get in the way of
+// using the instances.
+func recordStats(update func(*Stats)) {
+	if err := updateStats(update); err != nil {
+		log.WarningLog.Printf("could not update stats: %v", err)
+	}
+}
+
+func updateStats(update func(*Stats)) error {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	path, err := statsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	unlock, err := lockStats(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stats, err := LoadStats()
+	if err != nil {
+		return err
+	}
+	if stats.Since.IsZero() {
+		stats.Since = time.Now()
+	}
+	update(&stats)
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// lockStats creates a lock file next to the stats file at path, so processes updating the stats at the same time
+// don't lose each other's updates. It returns a function which releases the lock.
+func lockStats(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(statsLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock stats: %w", err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleStatsLock {
+			_ = os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for another process to release %s", lock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
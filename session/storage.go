@@ -2,10 +2,13 @@ package session
 
 import (
 	"claude-squad/config"
+	"claude-squad/session/git"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -16,13 +19,17 @@ type GitWorktreeData struct {
 	SessionName   string
 	BranchName    string
 	BaseCommitSHA string
+	// BaseBranch is the branch the worktree started from, which merges go into. Empty if it's not known.
+	BaseBranch string `json:",omitempty"`
 }
 
 // DiffStatsData represents the serializable data of a DiffStats
 type DiffStatsData struct {
-	Added   int
-	Removed int
-	Content string
+	Added        int
+	Removed      int
+	TotalAdded   int
+	TotalRemoved int
+	Content      string
 }
 
 // InstanceData represents the serializable data of an Instance
@@ -36,16 +43,50 @@ type InstanceData struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	AutoYes   bool
+	Prompt    string
+	Tags      []string
+	Pinned    bool
+
+	Description   string
+	PromptHistory []string
+	DiffFilter    string
+	DiffBaseline  string
+	Pushes        int
+	RunningSince  time.Time
 
 	Program   string
 	Worktree  GitWorktreeData
 	DiffStats DiffStatsData
 }
 
+// UIState is the state of the UI which is restored across restarts.
+type UIState struct {
+	// SelectedTitle is the title of the selected instance.
+	SelectedTitle string
+	// ActiveTab is the index of the active tab in the tabbed window.
+	ActiveTab int
+	// ManualOrder is set if the instances were arranged by hand, so they're stored in the order to list them in.
+	ManualOrder bool
+	// HidePaused is set if paused instances are hidden from the list.
+	HidePaused bool
+	// Workspace is the name of the workspace shown, or empty if every instance is shown.
+	Workspace string
+}
+
 // Storage handles saving and loading instances
 type Storage struct {
-	filePath  string
-	backupDir string
+	filePath      string
+	backupDir     string
+	stateFilePath string
+	// workspacesFilePath is the file the workspaces are saved in, which map names to the titles of their
+	// instances.
+	workspacesFilePath string
+	// mu serializes writes to the instances and workspaces files, since auto-saves write the instances in the
+	// background.
+	mu sync.Mutex
+	// generation counts the writes to the instances file other than auto-saves, so an auto-save can tell if its
+	// snapshot was overtaken. Guarded by mu.
+	generation uint64
 }
 
 // NewStorage creates a new storage instance
@@ -65,13 +106,120 @@ func NewStorage() (*Storage, error) {
 	}
 
 	return &Storage{
-		filePath:  filepath.Join(dir, "instances.json"),
-		backupDir: backupDir,
+		filePath:      filepath.Join(dir, "instances.json"),
+		backupDir:     backupDir,
+		stateFilePath: filepath.Join(dir, "state.json"),
+
+		workspacesFilePath: filepath.Join(dir, "workspaces.json"),
 	}, nil
 }
 
 // SaveInstances saves the list of instances to disk
 func (s *Storage) SaveInstances(instances []*Instance) error {
+	// Convert and save instances
+	data := make([]InstanceData, 0)
+	for _, instance := range instances {
+		if instance.Started() {
+			data = append(data, instance.ToInstanceData())
+		}
+	}
+	return s.saveInstanceData(data)
+}
+
+// AddInstance adds a started instance to storage without loading the other stored instances. Returns an error
+// if an instance with the same title is already stored.
+func (s *Storage) AddInstance(instance *Instance) error {
+	if !instance.Started() {
+		return fmt.Errorf("cannot store instance that has not been started")
+	}
+	data, err := s.LoadInstanceData()
+	if err != nil {
+		return err
+	}
+	for _, existing := range data {
+		if existing.Title == instance.Title {
+			return fmt.Errorf("instance already exists: %s", instance.Title)
+		}
+	}
+	return s.saveInstanceData(append(data, instance.ToInstanceData()))
+}
+
+// ImportInstanceData adds instances exported from another machine or backup. Worktrees and tmux sessions don't
+// transfer, so the instances are stored paused with new worktree paths, and their worktrees are recreated from
+// their branches when they're resumed. An instance whose title is already taken is stored with a numeric suffix.
+// Returns the titles the instances were stored under. Nothing is imported if an instance's branch is already
+// used by a stored instance or another imported one, since they'd share a worktree.
+func (s *Storage) ImportInstanceData(imported []InstanceData) ([]string, error) {
+	data, err := s.LoadInstanceData()
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(data))
+	branches := make(map[string]string, len(data))
+	for _, existing := range data {
+		taken[existing.Title] = true
+		branches[existing.Branch] = existing.Title
+	}
+	for _, instance := range imported {
+		if instance.Branch == "" {
+			continue
+		}
+		if owner, ok := branches[instance.Branch]; ok {
+			return nil, fmt.Errorf("%s uses branch %s, which is already used by %s", instance.Title, instance.Branch, owner)
+		}
+		branches[instance.Branch] = instance.Title
+	}
+
+	titles := make([]string, 0, len(imported))
+	for _, instance := range imported {
+		title := instance.Title
+		for n := 2; taken[title]; n++ {
+			title = fmt.Sprintf("%s-%d", instance.Title, n)
+		}
+		taken[title] = true
+
+		worktreePath, err := git.NewWorktreePath(title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get worktree path for %s: %w", title, err)
+		}
+		instance.Title = title
+		instance.Status = Paused
+		instance.Worktree.SessionName = title
+		instance.Worktree.WorktreePath = worktreePath
+		data = append(data, instance)
+		titles = append(titles, title)
+	}
+	return titles, s.saveInstanceData(data)
+}
+
+// SaveGeneration returns the number of times the instances were saved other than by AutoSaveInstanceData. Take it
+// when taking the snapshot to auto-save.
+func (s *Storage) SaveGeneration() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// AutoSaveInstanceData writes data to the instances file without backing up the current one first, or periodic
+// saves would fill the backups with near identical copies. It's safe to call from a background goroutine. If the
+// instances were saved since generation was taken with SaveGeneration, data is stale and isn't written, so it
+// can't bring back instances which were deleted in the meantime.
+func (s *Storage) AutoSaveInstanceData(data []InstanceData, generation uint64) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal instances: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		return nil
+	}
+	return os.WriteFile(s.filePath, jsonData, 0644)
+}
+
+// saveInstanceData backs up the current instances file and writes data to it.
+func (s *Storage) saveInstanceData(data []InstanceData) error {
 	// Create backup if file exists
 	if _, err := os.Stat(s.filePath); err == nil {
 		timestamp := time.Now().Format("20060102_150405")
@@ -84,28 +232,29 @@ func (s *Storage) SaveInstances(instances []*Instance) error {
 		}
 	}
 
-	// Convert and save instances
-	data := make([]InstanceData, 0)
-	for _, instance := range instances {
-		if instance.Started() {
-			data = append(data, instance.ToInstanceData())
-		}
-	}
+	return s.writeInstanceData(data)
+}
 
+// writeInstanceData writes data to the instances file.
+func (s *Storage) writeInstanceData(data []InstanceData) error {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal instances: %w", err)
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
 	return os.WriteFile(s.filePath, jsonData, 0644)
 }
 
-// LoadInstances loads the list of instances from disk
-func (s *Storage) LoadInstances() ([]*Instance, error) {
+// LoadInstanceData loads the serialized instances from disk without starting them. This is useful
+// for inspecting the stored sessions without touching tmux or git.
+func (s *Storage) LoadInstanceData() ([]InstanceData, error) {
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []*Instance{}, nil
+			return []InstanceData{}, nil
 		}
 		return nil, fmt.Errorf("failed to read instances: %w", err)
 	}
@@ -114,10 +263,29 @@ func (s *Storage) LoadInstances() ([]*Instance, error) {
 	if err := json.Unmarshal(data, &instanceData); err != nil {
 		return nil, fmt.Errorf("failed to parse instances: %w", err)
 	}
+	return instanceData, nil
+}
+
+// LoadInstances loads the list of instances from disk
+func (s *Storage) LoadInstances() ([]*Instance, error) {
+	return s.loadInstances(FromInstanceData)
+}
+
+// LoadInstancesDeferred loads the list of instances from disk without restoring their tmux sessions. Each is
+// restored when it's first used. See FromInstanceDataDeferred.
+func (s *Storage) LoadInstancesDeferred() ([]*Instance, error) {
+	return s.loadInstances(FromInstanceDataDeferred)
+}
+
+func (s *Storage) loadInstances(fromData func(InstanceData) (*Instance, error)) ([]*Instance, error) {
+	instanceData, err := s.LoadInstanceData()
+	if err != nil {
+		return nil, err
+	}
 
 	instances := make([]*Instance, len(instanceData))
 	for i, data := range instanceData {
-		instance, err := FromInstanceData(data)
+		instance, err := fromData(data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create instance %s: %w", data.Title, err)
 		}
@@ -127,7 +295,93 @@ func (s *Storage) LoadInstances() ([]*Instance, error) {
 	return instances, nil
 }
 
-// DeleteInstance removes an instance from storage
+// SaveUIState saves the UI state to disk
+func (s *Storage) SaveUIState(state UIState) error {
+	jsonData, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ui state: %w", err)
+	}
+	return os.WriteFile(s.stateFilePath, jsonData, 0644)
+}
+
+// LoadUIState loads the UI state from disk. Returns the zero state if none was saved.
+func (s *Storage) LoadUIState() (UIState, error) {
+	var state UIState
+	data, err := os.ReadFile(s.stateFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to read ui state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return UIState{}, fmt.Errorf("failed to parse ui state: %w", err)
+	}
+	return state, nil
+}
+
+// LoadWorkspaces loads the saved workspaces, which map names to the titles of their instances. Returns no
+// workspaces if none were saved.
+func (s *Storage) LoadWorkspaces() (map[string][]string, error) {
+	workspaces := make(map[string][]string)
+	data, err := os.ReadFile(s.workspacesFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return workspaces, nil
+		}
+		return nil, fmt.Errorf("failed to read workspaces: %w", err)
+	}
+	if err := json.Unmarshal(data, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed to parse workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+// SaveWorkspace saves a workspace with the titles of its instances, replacing any workspace with the same name.
+func (s *Storage) SaveWorkspace(name string, titles []string) error {
+	return s.updateWorkspaces(func(workspaces map[string][]string) {
+		workspaces[name] = titles
+	})
+}
+
+// RenameInWorkspaces replaces the title of a renamed instance in every workspace.
+func (s *Storage) RenameInWorkspaces(oldTitle, newTitle string) error {
+	return s.updateWorkspaces(func(workspaces map[string][]string) {
+		for _, titles := range workspaces {
+			if idx := slices.Index(titles, oldTitle); idx >= 0 {
+				titles[idx] = newTitle
+			}
+		}
+	})
+}
+
+// RemoveFromWorkspaces removes the titles of deleted instances from every workspace.
+func (s *Storage) RemoveFromWorkspaces(titles []string) error {
+	return s.updateWorkspaces(func(workspaces map[string][]string) {
+		for name, workspace := range workspaces {
+			workspaces[name] = slices.DeleteFunc(workspace, func(title string) bool {
+				return slices.Contains(titles, title)
+			})
+		}
+	})
+}
+
+func (s *Storage) updateWorkspaces(update func(map[string][]string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	workspaces, err := s.LoadWorkspaces()
+	if err != nil {
+		return err
+	}
+	update(workspaces)
+	jsonData, err := json.MarshalIndent(workspaces, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspaces: %w", err)
+	}
+	return os.WriteFile(s.workspacesFilePath, jsonData, 0644)
+}
+
+// DeleteInstance removes an instance from storage and the workspaces
 func (s *Storage) DeleteInstance(title string) error {
 	instances, err := s.LoadInstances()
 	if err != nil {
@@ -137,24 +391,44 @@ func (s *Storage) DeleteInstance(title string) error {
 	for i, instance := range instances {
 		if instance.Title == title {
 			instances = append(instances[:i], instances[i+1:]...)
-			return s.SaveInstances(instances)
+			if err := s.SaveInstances(instances); err != nil {
+				return err
+			}
+			return s.RemoveFromWorkspaces([]string{title})
 		}
 	}
 
 	return fmt.Errorf("instance not found: %s", title)
 }
 
-// UpdateInstance updates an existing instance in storage
+// DeleteInstances removes the instances with the given titles from storage and the workspaces without loading the
+// other stored instances. Titles which aren't stored are ignored.
+func (s *Storage) DeleteInstances(titles []string) error {
+	data, err := s.LoadInstanceData()
+	if err != nil {
+		return err
+	}
+
+	data = slices.DeleteFunc(data, func(d InstanceData) bool {
+		return slices.Contains(titles, d.Title)
+	})
+	if err := s.saveInstanceData(data); err != nil {
+		return err
+	}
+	return s.RemoveFromWorkspaces(titles)
+}
+
+// UpdateInstance updates an existing instance in storage without loading the other stored instances.
 func (s *Storage) UpdateInstance(instance *Instance) error {
-	instances, err := s.LoadInstances()
+	data, err := s.LoadInstanceData()
 	if err != nil {
 		return err
 	}
 
-	for i, existing := range instances {
+	for i, existing := range data {
 		if existing.Title == instance.Title {
-			instances[i] = instance
-			return s.SaveInstances(instances)
+			data[i] = instance.ToInstanceData()
+			return s.saveInstanceData(data)
 		}
 	}
 
@@ -164,10 +438,25 @@ func (s *Storage) UpdateInstance(instance *Instance) error {
 // DeleteAllInstances removes all stored instances and their backups
 func (s *Storage) DeleteAllInstances() error {
 	// Remove the main instances file
-	if err := os.Remove(s.filePath); err != nil && !os.IsNotExist(err) {
+	s.mu.Lock()
+	s.generation++
+	err := os.Remove(s.filePath)
+	s.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete instances file: %w", err)
 	}
 
+	// The ui state and workspaces refer to instances, so remove them too
+	if err := os.Remove(s.stateFilePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete ui state file: %w", err)
+	}
+	s.mu.Lock()
+	err = os.Remove(s.workspacesFilePath)
+	s.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete workspaces file: %w", err)
+	}
+
 	// Remove all backup files
 	entries, err := os.ReadDir(s.backupDir)
 	if err != nil {
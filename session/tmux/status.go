@@ -0,0 +1,96 @@
+package tmux
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// paneState is what a program is doing, as far as can be told from its pane.
+type paneState int
+
+const (
+	// paneUnknown means the content doesn't show what the program is doing, so HasUpdated falls back to
+	// checking whether the content changed.
+	paneUnknown paneState = iota
+	// paneWorking means the program is busy, ex. waiting for the model.
+	paneWorking
+	// paneIdle means the program is waiting for the next prompt.
+	paneIdle
+	// panePrompt means the program is asking the user to answer a question, ex. to allow an edit.
+	panePrompt
+)
+
+// statusParser detects the state of a specific program from the content of its pane.
+type statusParser interface {
+	Parse(content string) paneState
+}
+
+// parserFor returns the status parser for program, or nil if there's none and only the generic checks apply.
+func parserFor(program string) statusParser {
+	switch filepath.Base(programName(program)) {
+	case ProgramClaude:
+		return claudeParser{}
+	case ProgramAider:
+		return aiderParser{}
+	}
+	return nil
+}
+
+// programName returns the executable of program, its first word after any environment variable assignments.
+// It's empty if there's none.
+func programName(program string) string {
+	fields := strings.Fields(program)
+	for len(fields) > 0 && strings.Contains(fields[0], "=") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// claudeParser detects the state of claude. While it works, it shows a spinner with "esc to interrupt" above
+// the input box. Permission prompts list numbered options under a "Do you want to ...?" question.
+type claudeParser struct{}
+
+var claudePromptRe = regexp.MustCompile(`Do you want to [^\n]*\?[\s\S]*1\. Yes`)
+
+func (claudeParser) Parse(content string) paneState {
+	switch {
+	case claudePromptRe.MatchString(content):
+		return panePrompt
+	case strings.Contains(content, "esc to interrupt"):
+		return paneWorking
+	default:
+		return paneIdle
+	}
+}
+
+// aiderParser detects the state of aider. When it's waiting for input, the last line is its prompt, ex. "> " or
+// "architect> ". Questions end with a "(Y)es/(N)o" choice. Anything else is streamed output, which the generic
+// content check handles.
+type aiderParser struct{}
+
+var (
+	aiderInputRe    = regexp.MustCompile(`^[\w-]*>$`)
+	aiderQuestionRe = regexp.MustCompile(`\(Y\)es/\(N\)o[^\n]*$`)
+)
+
+func (aiderParser) Parse(content string) paneState {
+	last := lastLine(content)
+	switch {
+	case aiderQuestionRe.MatchString(last):
+		return panePrompt
+	case aiderInputRe.MatchString(last):
+		return paneIdle
+	default:
+		return paneUnknown
+	}
+}
+
+// lastLine returns the last non-blank line of content with surrounding whitespace removed.
+func lastLine(content string) string {
+	lines := strings.Split(strings.TrimRight(content, " \t\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
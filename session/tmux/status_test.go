@@ -0,0 +1,102 @@
+package tmux
+
+import "testing"
+
+// The captures below are what capture-pane returns for the programs, trimmed to the lines that matter.
+
+const claudeWorkingCapture = `> add a --verbose flag to the list command
+
+● I'll add the flag to the list command.
+
+● Read(main.go)
+  ⎿  Read 412 lines (ctrl+r to expand)
+
+✻ Pondering… (12s · ↑ 1.2k tokens · esc to interrupt)
+
+╭──────────────────────────────────────────────────────────────────────────────╮
+│ >                                                                            │
+╰──────────────────────────────────────────────────────────────────────────────╯
+  ? for shortcuts
+`
+
+const claudePromptCapture = `● Update(main.go)
+
+╭──────────────────────────────────────────────────────────────────────────────╮
+│ Edit file                                                                    │
+│ ╭──────────────────────────────────────────────────────────────────────────╮ │
+│ │ main.go                                                                  │ │
+│ │                                                                          │ │
+│ │ 57      listCmd.Flags().BoolVar(&verboseFlag, "verbose", false,          │ │
+│ │ 58          "Show each session's branch and path")                       │ │
+│ ╰──────────────────────────────────────────────────────────────────────────╯ │
+│ Do you want to make this edit to main.go?                                    │
+│ ❯ 1. Yes                                                                     │
+│   2. Yes, and don't ask again this session (shift+tab)                       │
+│   3. No, and tell Claude what to do differently (esc)                        │
+╰──────────────────────────────────────────────────────────────────────────────╯
+`
+
+const claudeIdleCapture = `● Added the --verbose flag. ` + "`cs list --verbose`" + ` now shows each session's branch and path.
+
+╭──────────────────────────────────────────────────────────────────────────────╮
+│ >                                                                            │
+╰──────────────────────────────────────────────────────────────────────────────╯
+  ? for shortcuts
+`
+
+const aiderWorkingCapture = `> add a --verbose flag to the list command
+
+I'll add the flag to the list command.
+
+main.go
+<<<<<<< SEARCH
+	listCmd.Flags().BoolVar(&jsonFlag, "json", false,
+`
+
+const aiderPromptCapture = `Aider v0.82.2
+Main model: sonnet with diff edit format, infinite output
+Git repo: .git with 42 files
+Repo-map: using 4096 tokens, auto refresh
+
+> /add main.go
+
+Add main.go to the chat? (Y)es/(N)o/(D)on't ask again [Yes]:
+`
+
+const aiderIdleCapture = `Applied edit to main.go
+Commit 1a2b3c4 feat: Add --verbose flag to the list command
+───────────────────────────────────────────────────────────────────────────────────
+main.go
+>
+
+
+`
+
+const aiderArchitectIdleCapture = `Tokens: 4.1k sent, 312 received. Cost: $0.02 message, $0.05 session.
+───────────────────────────────────────────────────────────────────────────────────
+architect>
+`
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  statusParser
+		content string
+		want    paneState
+	}{
+		{name: "claude working", parser: claudeParser{}, content: claudeWorkingCapture, want: paneWorking},
+		{name: "claude prompt", parser: claudeParser{}, content: claudePromptCapture, want: panePrompt},
+		{name: "claude idle", parser: claudeParser{}, content: claudeIdleCapture, want: paneIdle},
+		{name: "aider working", parser: aiderParser{}, content: aiderWorkingCapture, want: paneUnknown},
+		{name: "aider prompt", parser: aiderParser{}, content: aiderPromptCapture, want: panePrompt},
+		{name: "aider idle", parser: aiderParser{}, content: aiderIdleCapture, want: paneIdle},
+		{name: "aider architect idle", parser: aiderParser{}, content: aiderArchitectIdleCapture, want: paneIdle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parser.Parse(tt.content); got != tt.want {
+				t.Errorf("Parse() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
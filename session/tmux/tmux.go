@@ -2,6 +2,7 @@ package tmux
 
 import (
 	"bytes"
+	"claude-squad/homedir"
 	"claude-squad/log"
 	"context"
 	"crypto/sha256"
@@ -10,11 +11,13 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/charmbracelet/x/ansi"
 	"github.com/creack/pty"
 	"golang.org/x/term"
 )
@@ -53,13 +56,215 @@ type TmuxSession struct {
 	ctx    context.Context
 	cancel func()
 	wg     *sync.WaitGroup
+	// monitorWg waits for the goroutine resizing the pty, which has to stop before the pty is closed.
+	monitorWg *sync.WaitGroup
 }
 
-const TmuxPrefix = "claudesquad-"
+// SocketName is the name of the separate tmux server managed sessions run on when ConfigFile is set.
+const SocketName = "claudesquad"
+
+// ConfigFile is the tmux config managed sessions use. If it's set, they run on their own tmux server, so the
+// config doesn't change the user's other sessions and the user's config doesn't change ours. Set it with
+// SetConfigFile.
+var ConfigFile string
+
+// defaultConfig is written to the config file if it doesn't exist yet. It keeps tmux close to its defaults,
+// which is what attaching from claude-squad expects.
+const defaultConfig = `# tmux config for the sessions managed by claude-squad. They run on their own tmux server, so this doesn't
+# affect your other tmux sessions.
+set -g prefix C-b
+set -g mouse off
+set -g status on
+set -g history-limit 10000
+# Pass escape to the program straight away rather than waiting for a key sequence.
+set -s escape-time 0
+`
+
+// SetConfigFile makes managed sessions use the tmux config at path, writing a default one there if it doesn't
+// exist. A leading "~" is expanded to the home directory. An empty path keeps using the user's tmux server and
+// config. This should be called once at startup, before any sessions are created or restored.
+func SetConfigFile(path string) error {
+	if path == "" {
+		ConfigFile = ""
+		return nil
+	}
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create tmux config directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(defaultConfig), 0644); err != nil {
+			return fmt.Errorf("failed to write default tmux config: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to check tmux config file: %w", err)
+	}
+	ConfigFile = path
+	return nil
+}
 
+// tmuxArgs returns args prefixed with the options selecting our tmux server and config, if ConfigFile is set.
+func tmuxArgs(args ...string) []string {
+	if ConfigFile == "" {
+		return args
+	}
+	return append([]string{"-L", SocketName, "-f", ConfigFile}, args...)
+}
+
+// tmuxCommand returns the command running tmux with args on the server managed sessions use.
+func tmuxCommand(args ...string) *exec.Cmd {
+	return exec.Command("tmux", tmuxArgs(args...)...)
+}
+
+// TmuxPrefix is prepended to the names of the tmux sessions we manage. Change it with SetPrefix.
+var TmuxPrefix = "claudesquad-"
+
+// SetPrefix sets the prefix of managed tmux session names. This should be called once at startup, before any
+// sessions are created. An empty prefix keeps the default.
+func SetPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	// tmux uses '.' and ':' in target names, so they can't be part of session names.
+	if strings.ContainsAny(prefix, ".: \t\n") {
+		return fmt.Errorf("invalid tmux prefix %q: must not contain '.', ':' or whitespace", prefix)
+	}
+	TmuxPrefix = prefix
+	return nil
+}
+
+// ExtraPaneCommand is run in a second pane next to the program, ex. "bash" for a side terminal. Start doesn't
+// create the pane if it's empty.
+var ExtraPaneCommand string
+
+// SessionEnv are NAME=value environment variables set in new sessions. They're passed with -e, which needs
+// tmux 3.2 or later.
+var SessionEnv []string
+
+// envArgs returns the -e flags which set SessionEnv.
+func envArgs() []string {
+	args := make([]string, 0, 2*len(SessionEnv))
+	for _, entry := range SessionEnv {
+		args = append(args, "-e", entry)
+	}
+	return args
+}
+
+// DefaultPromptPatterns detect the permission prompts of claude and aider.
+var DefaultPromptPatterns = []string{
+	`Yes, and don't ask again this session`,
+	`\(Y\)es/\(N\)o`,
+}
+
+// promptPatterns are matched against pane content to tell whether the program is waiting for the user to
+// answer a prompt. They're DefaultPromptPatterns until SetPromptPatterns changes them.
+var promptPatterns = compilePromptPatterns(DefaultPromptPatterns)
+
+func compilePromptPatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// SetPromptPatterns sets the regular expressions which detect a prompt in any program. An empty list keeps the
+// defaults.
+func SetPromptPatterns(patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid prompt pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	promptPatterns = compiled
+	return nil
+}
+
+// SkipProgramCheck disables checking that a program is installed before starting it. Useful if the program is
+// a shell alias or function which isn't on the PATH.
+var SkipProgramCheck bool
+
+// ValidateProgram returns an error if the executable of program, its first word after any environment variable
+// assignments, can't be found on the PATH.
+func ValidateProgram(program string) error {
+	if SkipProgramCheck {
+		return nil
+	}
+	name := programName(program)
+	if name == "" {
+		return fmt.Errorf("no program given")
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("program %q not found, is it installed and on your PATH? (%w)", name, err)
+	}
+	return nil
+}
+
+// TrustScreenTimeout is how long Start waits for the "do you trust the files" screen before giving up.
+var TrustScreenTimeout = 5 * time.Second
+
+// TrustScreenPollInterval is how often Start checks for the trust screen.
+var TrustScreenPollInterval = 200 * time.Millisecond
+
+// AutoAcceptTrust makes Start accept the trust screen. If it's false, the screen is left for the user to answer
+// when they attach.
+var AutoAcceptTrust = true
+
+// StartMaxAttempts is how many times Start tries to create the tmux session before giving up.
+var StartMaxAttempts = 3
+
+// StartRetryBackoff is how long Start waits before retrying. It doubles after every failed attempt.
+var StartRetryBackoff = 500 * time.Millisecond
+
+// SetStartMaxAttempts sets how many times Start tries to create a session. Non-positive values keep the default.
+func SetStartMaxAttempts(attempts int) {
+	if attempts > 0 {
+		StartMaxAttempts = attempts
+	}
+}
+
+// SetTrustScreenPolling sets how long and how often Start polls for the trust screen. Non-positive values keep
+// the defaults.
+func SetTrustScreenPolling(timeout, interval time.Duration) {
+	if timeout > 0 {
+		TrustScreenTimeout = timeout
+	}
+	if interval > 0 {
+		TrustScreenPollInterval = interval
+	}
+}
+
+var (
+	tmuxNameSpaceRe  = regexp.MustCompile(`\s+`)
+	tmuxNameUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// toClaudeSquadTmuxName returns the tmux session name for a title. Whitespace is removed, as it always has been.
+// Anything else other than ASCII letters, digits, '_' and '-' is replaced with '_', since tmux reads '.' and ':' in
+// targets as window and pane separators, and a short hash of the title is appended so titles differing only in
+// those characters still get different sessions. Sessions older versions started under the name
+// legacyTmuxName returns are renamed by Restore.
 func toClaudeSquadTmuxName(str string) string {
-	re := regexp.MustCompile(`\s+`)
-	return fmt.Sprintf("%s%s", TmuxPrefix, re.ReplaceAllString(str, ""))
+	name := tmuxNameSpaceRe.ReplaceAllString(str, "")
+	if tmuxNameUnsafeRe.MatchString(name) {
+		sum := sha256.Sum256([]byte(str))
+		name = fmt.Sprintf("%s_%x", tmuxNameUnsafeRe.ReplaceAllString(name, "_"), sum[:4])
+	}
+	return fmt.Sprintf("%s%s", TmuxPrefix, name)
+}
+
+// legacyTmuxName returns the tmux session name older versions used for a title, which only had whitespace removed.
+func legacyTmuxName(str string) string {
+	return TmuxPrefix + tmuxNameSpaceRe.ReplaceAllString(str, "")
 }
 
 func NewTmuxSession(name string, program string) *TmuxSession {
@@ -70,22 +275,100 @@ func NewTmuxSession(name string, program string) *TmuxSession {
 	}
 }
 
+// SanitizedName returns the name of the session in tmux.
+func (t *TmuxSession) SanitizedName() string {
+	return t.sanitizedName
+}
+
+// AttachCommand returns the shell command which attaches to the session directly, ex. from another terminal. The
+// "=" makes tmux match the name exactly rather than as a prefix.
+func (t *TmuxSession) AttachCommand() string {
+	server := ""
+	if ConfigFile != "" {
+		server = fmt.Sprintf("-L %s ", SocketName)
+	}
+	return fmt.Sprintf("tmux %sattach-session -t '=%s'", server, strings.ReplaceAll(t.sanitizedName, "'", `'\''`))
+}
+
+// AttachInTerminal opens a new window of the terminal emulator run by terminal, ex. "kitty" or
+// "gnome-terminal --", attached to the session. The tmux command is appended to the terminal's arguments. It
+// doesn't wait for the window to close.
+func (t *TmuxSession) AttachInTerminal(terminal string) error {
+	args := strings.Fields(terminal)
+	if len(args) == 0 {
+		return fmt.Errorf("terminal command is empty")
+	}
+	args = append(args, "tmux")
+	args = append(args, tmuxArgs("attach-session", "-t", "="+t.sanitizedName)...)
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open terminal %s: %w", args[0], err)
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.WarningLog.Printf("terminal %s exited with error: %v", args[0], err)
+		}
+	}()
+	return nil
+}
+
 // Start creates and starts a new tmux session, then attaches to it. Program is the command to run in
 // the session (ex. claude). workdir is the git worktree directory.
 func (t *TmuxSession) Start(program string, workDir string) error {
-	// Check if the session already exists
+	// Check if the session already exists. Retrying won't help with this.
 	if DoesSessionExist(t.sanitizedName) {
 		return fmt.Errorf("tmux session already exists: %s", t.sanitizedName)
 	}
 
+	// Creating the session can time out on a loaded machine, so retry with backoff.
+	var errs []error
+	backoff := StartRetryBackoff
+	for attempt := 1; attempt <= StartMaxAttempts; attempt++ {
+		err := t.createSession(program, workDir)
+		if err == nil {
+			break
+		}
+		errs = append(errs, fmt.Errorf("attempt %d: %w", attempt, err))
+		log.WarningLog.Printf("failed to start tmux session %s (attempt %d/%d): %v",
+			t.sanitizedName, attempt, StartMaxAttempts, err)
+		// Clean up anything the failed attempt left behind before trying again.
+		if DoesSessionExist(t.sanitizedName) {
+			if cleanupErr := tmuxCommand("kill-session", "-t", t.sanitizedName).Run(); cleanupErr != nil {
+				errs = append(errs, fmt.Errorf("attempt %d cleanup: %w", attempt, cleanupErr))
+			}
+		}
+		if attempt == StartMaxAttempts {
+			return fmt.Errorf("failed to start tmux session after %d attempts: %w", attempt, errors.Join(errs...))
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	if ExtraPaneCommand != "" {
+		// -d keeps the program's pane active, since that's the one we capture and send keys to.
+		args := append([]string{"split-window", "-d", "-h", "-t", t.sanitizedName, "-c", workDir}, envArgs()...)
+		cmd := tmuxCommand(append(args, ExtraPaneCommand)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			// The program is running fine, so don't fail the whole session over the extra pane.
+			log.ErrorLog.Printf("failed to create extra pane in %s: %s (%v)", t.sanitizedName, strings.TrimSpace(string(output)), err)
+		}
+	}
+
+	t.handleTrustScreen(program)
+	return nil
+}
+
+// createSession creates the detached tmux session running program and attaches our pty to it.
+func (t *TmuxSession) createSession(program string, workDir string) error {
 	// Create a new detached tmux session and start claude in it
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, program)
+	args := append([]string{"new-session", "-d", "-s", t.sanitizedName, "-c", workDir}, envArgs()...)
+	cmd := tmuxCommand(append(args, program)...)
 
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
 		// Cleanup any partially created session if any exists.
 		if DoesSessionExist(t.sanitizedName) {
-			cleanupCmd := exec.Command("tmux", "kill-session", "-t", t.sanitizedName)
+			cleanupCmd := tmuxCommand("kill-session", "-t", t.sanitizedName)
 			if cleanupErr := cleanupCmd.Run(); cleanupErr != nil {
 				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
 			}
@@ -99,11 +382,9 @@ func (t *TmuxSession) Start(program string, workDir string) error {
 	for !DoesSessionExist(t.sanitizedName) {
 		select {
 		case <-timeout:
-			// Cleanup on window size update failure
-			if cleanupErr := t.Close(); cleanupErr != nil {
-				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
-			}
-			return fmt.Errorf("timed out waiting for tmux session: %v", err)
+			// Start kills the session if it shows up late.
+			ptmx.Close()
+			return fmt.Errorf("timed out waiting for tmux session")
 		default:
 			time.Sleep(time.Millisecond * 10)
 		}
@@ -117,52 +398,140 @@ func (t *TmuxSession) Start(program string, workDir string) error {
 		}
 		return fmt.Errorf("error restoring tmux session: %w", err)
 	}
+	return nil
+}
 
+// RestartProgram kills the program running in the session's pane and starts program again in its place. The
+// session and its working directory are kept.
+func (t *TmuxSession) RestartProgram(program string) error {
+	args := append([]string{"respawn-pane", "-k", "-t", t.sanitizedName}, envArgs()...)
+	cmd := tmuxCommand(append(args, program)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error restarting program: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+	t.program = program
+	t.monitor = newStatusMonitor(t.program)
+	t.handleTrustScreen(program)
+	return nil
+}
+
+// handleTrustScreen deals with the "do you trust the files" screen shown when claude or aider start.
+func (t *TmuxSession) handleTrustScreen(program string) {
+	if !AutoAcceptTrust {
+		return
+	}
 	if program == ProgramClaude || strings.HasPrefix(program, ProgramAider) {
 		searchString := "Do you trust the files in this folder?"
 		tapFunc := t.TapEnter
-		iterations := 5
 		if program != ProgramClaude {
 			searchString = "Open documentation url for more info"
 			tapFunc = t.TapDAndEnter
-			iterations = 10 // Aider takes longer to start :/
 		}
-		// Deal with "do you trust the files" screen by sending an enter keystroke.
-		for i := 0; i < iterations; i++ {
-			time.Sleep(200 * time.Millisecond)
+		// Deal with "do you trust the files" screen by sending an enter keystroke. Keep polling until the
+		// timeout since slow machines can take a while to show it.
+		found := false
+		deadline := time.Now().Add(TrustScreenTimeout)
+		for !found && time.Now().Before(deadline) {
+			time.Sleep(TrustScreenPollInterval)
 			content, err := t.CapturePaneContent()
 			if err != nil {
 				log.ErrorLog.Printf("could not check 'do you trust the files screen': %v", err)
+				continue
 			}
 			if strings.Contains(content, searchString) {
+				found = true
 				if err := tapFunc(); err != nil {
 					log.ErrorLog.Printf("could not tap enter on trust screen: %v", err)
 				}
-				break
 			}
 		}
+		if !found {
+			log.InfoLog.Printf("trust screen for %s was not detected within %s", t.sanitizedName, TrustScreenTimeout)
+		}
 	}
+}
+
+// Rename renames the tmux session. If the session is not running (ex. the instance is paused), only the
+// names are updated so the next Start uses the new name.
+func (t *TmuxSession) Rename(name string) error {
+	sanitizedName := toClaudeSquadTmuxName(name)
+	if sanitizedName == t.sanitizedName {
+		t.Name = name
+		return nil
+	}
+	if DoesSessionExist(sanitizedName) {
+		return fmt.Errorf("tmux session already exists: %s", sanitizedName)
+	}
+
+	if DoesSessionExist(t.sanitizedName) {
+		cmd := tmuxCommand("rename-session", "-t", t.sanitizedName, sanitizedName)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("error renaming tmux session: %s (%w)", output, err)
+		}
+	}
+
+	t.Name = name
+	t.sanitizedName = sanitizedName
 	return nil
 }
 
+// startAttachPty starts a tmux client attached to the session in a new PTY. It's a variable so tests can make it
+// fail.
+var startAttachPty = func(sanitizedName string) (*os.File, error) {
+	return pty.Start(tmuxCommand("attach-session", "-t", sanitizedName))
+}
+
+// restoreTerminal restores the terminal to a state saved before attaching. It's a variable so tests don't touch
+// the real terminal.
+var restoreTerminal = term.Restore
+
+// makeRaw puts the terminal in raw mode for attaching. It's a variable so tests don't touch the real terminal.
+var makeRaw = term.MakeRaw
+
+// terminalSize returns the size of the terminal in columns and rows. It's a variable so tests can resize it.
+var terminalSize = func() (int, int, error) {
+	return term.GetSize(int(os.Stdin.Fd()))
+}
+
 // Restore attaches to an existing session and restores the window size
 func (t *TmuxSession) Restore() error {
-	ptmx, err := pty.Start(exec.Command("tmux", "attach-session", "-t", t.sanitizedName))
+	t.migrateLegacyName()
+	ptmx, err := startAttachPty(t.sanitizedName)
 	if err != nil {
 		return fmt.Errorf("error opening PTY: %w", err)
 	}
 	t.ptmx = ptmx
-	t.monitor = newStatusMonitor()
+	t.monitor = newStatusMonitor(t.program)
 	return nil
 }
 
+// migrateLegacyName renames the session to its current name if it was started by an older version under its
+// legacy name, see toClaudeSquadTmuxName. If renaming fails, the legacy name is used as is.
+func (t *TmuxSession) migrateLegacyName() {
+	legacy := legacyTmuxName(t.Name)
+	if legacy == t.sanitizedName || DoesSessionExist(t.sanitizedName) || !DoesSessionExist(legacy) {
+		return
+	}
+	// "=" matches the name exactly, even if it has '.' or ':' in it.
+	cmd := tmuxCommand("rename-session", "-t", "="+legacy, t.sanitizedName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.WarningLog.Printf("could not rename tmux session %s to %s, using the old name: %s (%v)",
+			legacy, t.sanitizedName, strings.TrimSpace(string(output)), err)
+		t.sanitizedName = legacy
+		return
+	}
+	log.InfoLog.Printf("renamed tmux session %s to %s", legacy, t.sanitizedName)
+}
+
 type statusMonitor struct {
 	// Store hashes to save memory.
 	prevOutputHash []byte
+	// parser detects the state of the program from its pane. It's nil for programs without one.
+	parser statusParser
 }
 
-func newStatusMonitor() *statusMonitor {
-	return &statusMonitor{}
+func newStatusMonitor(program string) *statusMonitor {
+	return &statusMonitor{parser: parserFor(program)}
 }
 
 // hash hashes the string.
@@ -196,8 +565,9 @@ func (t *TmuxSession) SendKeys(keys string) error {
 	return err
 }
 
-// HasUpdated checks if the tmux pane content has changed since the last tick. It also returns true if
-// the tmux pane has a prompt for aider or claude code.
+// HasUpdated checks if the program is working, and if the pane shows a prompt. For claude and aider, this is
+// parsed from the pane. Otherwise, or if the parser can't tell, the program is working if the content changed
+// since the last tick, and a prompt is detected if the pane matches one of the prompt patterns.
 func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 	content, err := t.CapturePaneContent()
 	if err != nil {
@@ -205,30 +575,55 @@ func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 		return false, false
 	}
 
-	// Only set hasPrompt for claude and aider. Use these strings to check for a prompt.
-	if t.program == ProgramClaude {
-		hasPrompt = strings.Contains(content, "Yes, and don't ask again this session")
-	} else if strings.HasPrefix(t.program, ProgramAider) {
-		hasPrompt = strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
+	for _, re := range promptPatterns {
+		if re.MatchString(content) {
+			hasPrompt = true
+			break
+		}
 	}
 
-	if !bytes.Equal(t.monitor.hash(content), t.monitor.prevOutputHash) {
+	changed := !bytes.Equal(t.monitor.hash(content), t.monitor.prevOutputHash)
+	if changed {
 		t.monitor.prevOutputHash = t.monitor.hash(content)
-		return true, hasPrompt
 	}
-	return false, hasPrompt
+
+	if t.monitor.parser != nil {
+		switch t.monitor.parser.Parse(ansi.Strip(content)) {
+		case paneWorking:
+			return true, hasPrompt
+		case paneIdle:
+			return false, hasPrompt
+		case panePrompt:
+			return false, true
+		}
+	}
+	return changed, hasPrompt
 }
 
+// Attach attaches to the tmux session interactively, forwarding stdin to the pane.
 func (t *TmuxSession) Attach() (chan struct{}, error) {
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	return t.attach(false)
+}
+
+// AttachReadOnly attaches to the tmux session without forwarding stdin to the pane. Ctrl+q still detaches.
+func (t *TmuxSession) AttachReadOnly() (chan struct{}, error) {
+	return t.attach(true)
+}
+
+func (t *TmuxSession) attach(readOnly bool) (chan struct{}, error) {
+	oldState, err := makeRaw(int(os.Stdin.Fd()))
 	if err != nil {
 		return nil, fmt.Errorf("error making terminal raw: %v", err)
 	}
 	t.oldState = oldState
 	t.attachCh = make(chan struct{})
+	// The terminal may have been resized while we were detached. Resize the pane before copying its output so
+	// the first screen is drawn at the right size.
+	t.resizeToTerminal()
 
 	t.wg = &sync.WaitGroup{}
 	t.wg.Add(1)
+	t.monitorWg = &sync.WaitGroup{}
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
 	// The first goroutine should terminate when the ptmx is closed. We use the
@@ -263,14 +658,15 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 			// Nuke the first bytes of stdin, up to 64, to prevent tmux from reading it.
 			// When we attach, there tends to be terminal control sequences like ?[?62c0;95;0c or
 			// ]10;rgb:f8f8f8. The control sequences depend on the terminal (warp vs iterm). We should use regex ideally
-			// but this works well for now. Log this for debugging.
+			// but this works well for now. Log this for debugging. The bytes are hex encoded and only logged at debug
+			// level since they could be keystrokes.
 			//
 			// There seems to always be control characters, but I think it's possible for there not to be. The heuristic
 			// here can be: if there's characters within 50ms, then assume they are control characters and nuke them.
 			select {
 			case <-timeoutCh:
 			default:
-				log.ErrorLog.Printf("nuked first stdin: %s", buf[:nr])
+				log.DebugLog.Printf("nuked first stdin: %x", buf[:nr])
 				continue
 			}
 
@@ -283,7 +679,10 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 				return
 			}
 
-			// Forward other input to tmux
+			// Forward other input to tmux unless we're only watching.
+			if readOnly {
+				continue
+			}
 			_, _ = t.ptmx.Write(buf[:nr])
 		}
 	}()
@@ -292,38 +691,49 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 	return t.attachCh, nil
 }
 
-// Detach disconnects from the current tmux session
-func (t *TmuxSession) Detach() (err error) {
-	// TODO: control flow is a bit messy here. If there's an error,
-	// I'm not sure if we get into a bad state. Needs testing.
+// Detach disconnects from the current tmux session. Every step is attempted even if an earlier one fails, so the
+// terminal is always handed back to the UI and the attach goroutines always stop. The errors are joined.
+func (t *TmuxSession) Detach() error {
 	defer func() {
-		close(t.attachCh)
+		// Close the channel last, since the caller may attach again as soon as it's closed.
+		attachCh := t.attachCh
 		t.attachCh = nil
 		t.oldState = nil
 		t.cancel = nil
 		t.ctx = nil
 		t.wg = nil
+		t.monitorWg = nil
+		close(attachCh)
 	}()
 
-	// Close the attached pty session.
+	var errs []error
+	// Cancel goroutines created by Attach. The one resizing the pty must be done before it's closed and replaced.
+	if t.cancel != nil {
+		t.cancel()
+	}
+	if t.monitorWg != nil {
+		t.monitorWg.Wait()
+	}
+	// Close the attached pty session. The goroutine copying its output dies on EOF due to the ptmx closing.
 	if err := t.ptmx.Close(); err != nil {
-		return fmt.Errorf("error closing attach pty session: %w", err)
+		errs = append(errs, fmt.Errorf("error closing attach pty session: %w", err))
+	}
+	if t.wg != nil {
+		t.wg.Wait()
 	}
-	// Attach goroutines should die on EOF due to the ptmx closing. Call
-	// t.Restore to set a new t.ptmx.
+	// Call t.Restore to set a new t.ptmx.
 	if err := t.Restore(); err != nil {
-		return err
+		errs = append(errs, err)
 	}
-	// Yeild the stdin/stdout back to the UI.
-	if err := term.Restore(int(os.Stdin.Fd()), t.oldState); err != nil {
-		return fmt.Errorf("error restoring terminal state: %w", err)
+	// Yield the stdin/stdout back to the UI. A terminal left in raw mode is unusable, so this is done whatever
+	// happened to the pty.
+	if t.oldState != nil {
+		if err := restoreTerminal(int(os.Stdin.Fd()), t.oldState); err != nil {
+			errs = append(errs, fmt.Errorf("error restoring terminal state: %w", err))
+		}
 	}
 
-	// Cancel goroutines created by Attach.
-	t.cancel()
-	t.wg.Wait()
-
-	return nil
+	return errors.Join(errs...)
 }
 
 // Close terminates the tmux session and cleans up resources
@@ -337,7 +747,7 @@ func (t *TmuxSession) Close() error {
 		t.ptmx = nil
 	}
 
-	cmd := exec.Command("tmux", "kill-session", "-t", t.sanitizedName)
+	cmd := tmuxCommand("kill-session", "-t", t.sanitizedName)
 	if err := cmd.Run(); err != nil {
 		errs = append(errs, fmt.Errorf("error killing tmux session: %w", err))
 	}
@@ -362,6 +772,19 @@ func (t *TmuxSession) SetDetachedSize(width, height int) error {
 	return t.updateWindowSize(width, height)
 }
 
+// resizeToTerminal resizes the attached pane to the current size of the terminal. Failures are only logged, the
+// pane keeps its size until the next resize.
+func (t *TmuxSession) resizeToTerminal() {
+	cols, rows, err := terminalSize()
+	if err != nil {
+		log.ErrorLog.Printf("failed to update window size: %v", err)
+		return
+	}
+	if err := t.updateWindowSize(cols, rows); err != nil {
+		log.ErrorLog.Printf("failed to update window size: %v", err)
+	}
+}
+
 // updateWindowSize updates the window size of the PTY.
 func (t *TmuxSession) updateWindowSize(cols, rows int) error {
 	return pty.Setsize(t.ptmx, &pty.Winsize{
@@ -375,14 +798,14 @@ func (t *TmuxSession) updateWindowSize(cols, rows int) error {
 // DoesSessionExist checks if a tmux session exists
 func DoesSessionExist(name string) bool {
 	// Using "-t name" does a prefix match, which is wrong. `-t=` does an exact match.
-	existsCmd := exec.Command("tmux", "has-session", fmt.Sprintf("-t=%s", name))
+	existsCmd := tmuxCommand("has-session", fmt.Sprintf("-t=%s", name))
 	return existsCmd.Run() == nil
 }
 
 // CapturePaneContent captures the content of the tmux pane
 func (t *TmuxSession) CapturePaneContent() (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-t", t.sanitizedName)
+	cmd := tmuxCommand("capture-pane", "-p", "-e", "-J", "-t", t.sanitizedName)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("error capturing pane content: %v", err)
@@ -394,7 +817,7 @@ func (t *TmuxSession) CapturePaneContent() (string, error) {
 // start and end specify the starting and ending line numbers (use "-" for the start/end of history)
 func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
-	cmd := exec.Command("tmux", "capture-pane", "-p", "-e", "-J", "-S", start, "-E", end, "-t", t.sanitizedName)
+	cmd := tmuxCommand("capture-pane", "-p", "-e", "-J", "-S", start, "-E", end, "-t", t.sanitizedName)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to capture tmux pane content with options: %v", err)
@@ -402,10 +825,10 @@ func (t *TmuxSession) CapturePaneContentWithOptions(start, end string) (string,
 	return string(output), nil
 }
 
-// CleanupSessions kills all tmux sessions that start with "session-"
+// CleanupSessions kills all tmux sessions that start with TmuxPrefix
 func CleanupSessions() error {
 	// First try to list sessions
-	cmd := exec.Command("tmux", "ls")
+	cmd := tmuxCommand("ls")
 	output, err := cmd.Output()
 
 	// If there's an error and it's because no server is running, that's fine
@@ -417,11 +840,12 @@ func CleanupSessions() error {
 		return fmt.Errorf("failed to list tmux sessions: %v", err)
 	}
 
-	re := regexp.MustCompile(fmt.Sprintf(`^%s\d+`, TmuxPrefix))
+	// Each line of `tmux ls` starts with the session name followed by a colon.
+	re := regexp.MustCompile(fmt.Sprintf(`(?m)^%s[^:]*`, regexp.QuoteMeta(TmuxPrefix)))
 	matches := re.FindAllString(string(output), -1)
 
 	for _, match := range matches {
-		cmd := exec.Command("tmux", "kill-session", "-t", match)
+		cmd := tmuxCommand("kill-session", "-t", match)
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to kill tmux session %s: %v", match, err)
 		}
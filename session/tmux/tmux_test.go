@@ -0,0 +1,370 @@
+package tmux
+
+import (
+	"claude-squad/log"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+func TestMain(m *testing.M) {
+	log.Initialize(false)
+	code := m.Run()
+	log.Close()
+	os.Exit(code)
+}
+
+// useTestTmuxServer points tmux at a server of the test's own, so the user's sessions are never touched, and
+// kills it when the test ends. The test is skipped if tmux isn't installed.
+func useTestTmuxServer(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+	// TMUX would make tmux use the server the tests run in rather than one in TMUX_TMPDIR.
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Cleanup(func() { _ = tmuxCommand("kill-server").Run() })
+}
+
+// newAttachedSession returns a session in the state attach leaves it in, with a pipe standing in for the pty.
+func newAttachedSession(t *testing.T) (*TmuxSession, context.Context) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TmuxSession{
+		sanitizedName: "claudesquad-test",
+		ptmx:          r,
+		attachCh:      make(chan struct{}),
+		oldState:      &term.State{},
+		ctx:           ctx,
+		cancel:        cancel,
+		wg:            &sync.WaitGroup{},
+	}, ctx
+}
+
+// stubDetachDeps replaces the pty and terminal functions used by Detach for the duration of the test.
+func stubDetachDeps(t *testing.T, attachErr, restoreErr error) *bool {
+	origStart, origRestore := startAttachPty, restoreTerminal
+	t.Cleanup(func() {
+		startAttachPty, restoreTerminal = origStart, origRestore
+	})
+
+	restored := false
+	startAttachPty = func(string) (*os.File, error) {
+		if attachErr != nil {
+			return nil, attachErr
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() {
+			_ = r.Close()
+			_ = w.Close()
+		})
+		return r, nil
+	}
+	restoreTerminal = func(int, *term.State) error {
+		restored = true
+		return restoreErr
+	}
+	return &restored
+}
+
+func TestDetach(t *testing.T) {
+	attachErr := errors.New("tmux server exited")
+	restoreErr := errors.New("bad file descriptor")
+
+	tests := []struct {
+		name        string
+		closePtyErr bool
+		attachErr   error
+		restoreErr  error
+		wantErrs    []string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:      "reattaching fails",
+			attachErr: attachErr,
+			wantErrs:  []string{"tmux server exited"},
+		},
+		{
+			name:        "closing the pty fails",
+			closePtyErr: true,
+			wantErrs:    []string{"error closing attach pty session"},
+		},
+		{
+			name:       "restoring the terminal fails",
+			restoreErr: restoreErr,
+			wantErrs:   []string{"error restoring terminal state"},
+		},
+		{
+			name:        "everything fails",
+			closePtyErr: true,
+			attachErr:   attachErr,
+			restoreErr:  restoreErr,
+			wantErrs:    []string{"error closing attach pty session", "tmux server exited", "error restoring terminal state"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored := stubDetachDeps(t, tt.attachErr, tt.restoreErr)
+			session, ctx := newAttachedSession(t)
+			attachCh := session.attachCh
+			if tt.closePtyErr {
+				// Closing an already closed file fails.
+				_ = session.ptmx.Close()
+			}
+
+			err := session.Detach()
+
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("Detach() returned error: %v", err)
+			}
+			for _, want := range tt.wantErrs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("Detach() error = %v, want it to contain %q", err, want)
+				}
+			}
+			if !*restored {
+				t.Error("terminal state was not restored")
+			}
+			if ctx.Err() == nil {
+				t.Error("attach goroutines were not cancelled")
+			}
+			select {
+			case <-attachCh:
+			default:
+				t.Error("attach channel was not closed")
+			}
+			if session.attachCh != nil || session.oldState != nil || session.wg != nil {
+				t.Error("attach state was not cleared")
+			}
+		})
+	}
+}
+
+func TestToClaudeSquadTmuxName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "feature", want: "claudesquad-feature"},
+		{title: "fix the bug", want: "claudesquad-fixthebug"},
+		{title: "snake_case-and-dashes", want: "claudesquad-snake_case-and-dashes"},
+	}
+	for _, tt := range tests {
+		if got := toClaudeSquadTmuxName(tt.title); got != tt.want {
+			t.Errorf("toClaudeSquadTmuxName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+
+	unsafe := []string{"v1.2", "host:port", "feature/login", "ünïcödé", "日本語", "a.b", "a:b", "a/b", "a_b"}
+	seen := make(map[string]string)
+	for _, title := range unsafe {
+		got := toClaudeSquadTmuxName(title)
+		if !strings.HasPrefix(got, TmuxPrefix) {
+			t.Errorf("toClaudeSquadTmuxName(%q) = %q, want prefix %q", title, got, TmuxPrefix)
+		}
+		if strings.ContainsAny(strings.TrimPrefix(got, TmuxPrefix), ".:/ ") {
+			t.Errorf("toClaudeSquadTmuxName(%q) = %q, contains characters tmux can't target", title, got)
+		}
+		for _, r := range got {
+			if r > 127 {
+				t.Errorf("toClaudeSquadTmuxName(%q) = %q, contains non-ASCII characters", title, got)
+				break
+			}
+		}
+		if other, ok := seen[got]; ok {
+			t.Errorf("toClaudeSquadTmuxName(%q) and toClaudeSquadTmuxName(%q) are both %q", title, other, got)
+		}
+		seen[got] = title
+		if again := toClaudeSquadTmuxName(title); again != got {
+			t.Errorf("toClaudeSquadTmuxName(%q) isn't stable: %q then %q", title, got, again)
+		}
+	}
+}
+
+func TestRestoreLegacyName(t *testing.T) {
+	useTestTmuxServer(t)
+	stubDetachDeps(t, nil, nil)
+
+	const title = "feature/login"
+	legacy := legacyTmuxName(title)
+	if legacy == toClaudeSquadTmuxName(title) {
+		t.Fatalf("the legacy and current names of %q are both %q", title, legacy)
+	}
+	if output, err := tmuxCommand("new-session", "-d", "-s", legacy, "sh").CombinedOutput(); err != nil {
+		t.Fatalf("failed to start tmux session: %s (%v)", output, err)
+	}
+
+	session := NewTmuxSession(title, "sh")
+	if err := session.Restore(); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+	if got, want := session.SanitizedName(), toClaudeSquadTmuxName(title); got != want {
+		t.Errorf("restored session is named %q, want %q", got, want)
+	}
+	if !DoesSessionExist(session.SanitizedName()) {
+		t.Errorf("tmux session %q doesn't exist after restoring", session.SanitizedName())
+	}
+	if DoesSessionExist(legacy) {
+		t.Errorf("tmux session %q still exists under its legacy name", legacy)
+	}
+
+	// A session under the current name is used as is.
+	again := NewTmuxSession(title, "sh")
+	if err := again.Restore(); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+	if got, want := again.SanitizedName(), toClaudeSquadTmuxName(title); got != want {
+		t.Errorf("restored session is named %q, want %q", got, want)
+	}
+}
+
+// stubAttachDeps replaces the terminal and pty functions used by Attach and Detach for the duration of the test,
+// and stands pipes in for stdin and stdout. Each pty Restore opens is a real one, and the terminal side of the
+// latest is sent on the returned channel so its size can be checked. setSize resizes the fake terminal, and
+// input writes to stdin.
+func stubAttachDeps(t *testing.T) (ttys chan *os.File, setSize func(cols, rows int), input *os.File) {
+	if _, _, err := pty.Open(); err != nil {
+		t.Skipf("ptys aren't supported: %v", err)
+	}
+	origStart, origRestore, origMakeRaw, origSize := startAttachPty, restoreTerminal, makeRaw, terminalSize
+	origStdin, origStdout := os.Stdin, os.Stdout
+	t.Cleanup(func() {
+		startAttachPty, restoreTerminal, makeRaw, terminalSize = origStart, origRestore, origMakeRaw, origSize
+		os.Stdin, os.Stdout = origStdin, origStdout
+	})
+
+	ttys = make(chan *os.File, 4)
+	startAttachPty = func(string) (*os.File, error) {
+		ptmx, tty, err := pty.Open()
+		if err != nil {
+			return nil, err
+		}
+		// Draw something now and then, like tmux does. Resizing puts ptmx in blocking mode, so Detach closing it
+		// only stops the attach goroutine once there's something to read.
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(10 * time.Millisecond):
+					_, _ = tty.Write([]byte("."))
+				}
+			}
+		}()
+		t.Cleanup(func() {
+			close(done)
+			_ = ptmx.Close()
+			_ = tty.Close()
+		})
+		ttys <- tty
+		return ptmx, nil
+	}
+	restoreTerminal = func(int, *term.State) error { return nil }
+	makeRaw = func(int) (*term.State, error) { return &term.State{}, nil }
+
+	var mu sync.Mutex
+	cols, rows := 80, 24
+	terminalSize = func() (int, int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return cols, rows, nil
+	}
+	setSize = func(c, r int) {
+		mu.Lock()
+		defer mu.Unlock()
+		cols, rows = c, r
+	}
+
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdoutR, stdout, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	go func() { _, _ = io.Copy(io.Discard, stdoutR) }()
+	t.Cleanup(func() {
+		_ = input.Close()
+		_ = stdout.Close()
+	})
+	os.Stdin, os.Stdout = stdin, stdout
+	return ttys, setSize, input
+}
+
+// waitForSize fails the test if tty doesn't reach the size within a second.
+func waitForSize(t *testing.T, tty *os.File, cols, rows int) {
+	t.Helper()
+	var gotRows, gotCols int
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var err error
+		if gotRows, gotCols, err = pty.Getsize(tty); err != nil {
+			t.Fatalf("failed to get the pty size: %v", err)
+		}
+		if gotCols == cols && gotRows == rows {
+			return
+		}
+	}
+	t.Errorf("pty is %dx%d, want %dx%d", gotCols, gotRows, cols, rows)
+}
+
+func TestAttachResizes(t *testing.T) {
+	ttys, setSize, input := stubAttachDeps(t)
+	session := NewTmuxSession("test", "claude")
+	if err := session.Restore(); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+
+	// detach presses ctrl+q once attach has stopped discarding the terminal's replies, and waits for it to detach.
+	detach := func(attachCh chan struct{}) {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := input.Write([]byte{17}); err != nil {
+			t.Fatalf("failed to press ctrl+q: %v", err)
+		}
+		select {
+		case <-attachCh:
+		case <-time.After(time.Second):
+			t.Fatal("ctrl+q didn't detach")
+		}
+	}
+
+	for i, size := range []struct{ cols, rows, resizedCols, resizedRows int }{
+		{cols: 100, rows: 30, resizedCols: 120, resizedRows: 40},
+		// The terminal was resized while detached.
+		{cols: 90, rows: 20, resizedCols: 110, resizedRows: 35},
+	} {
+		tty := <-ttys
+		setSize(size.cols, size.rows)
+		attachCh, err := session.Attach()
+		if err != nil {
+			t.Fatalf("Attach() #%d returned error: %v", i+1, err)
+		}
+		waitForSize(t, tty, size.cols, size.rows)
+
+		setSize(size.resizedCols, size.resizedRows)
+		raiseWinch(t)
+		waitForSize(t, tty, size.resizedCols, size.resizedRows)
+		detach(attachCh)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package tmux
+
+import (
+	"syscall"
+	"testing"
+)
+
+// raiseWinch tells the process its terminal was resized.
+func raiseWinch(t *testing.T) {
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatalf("failed to raise SIGWINCH: %v", err)
+	}
+}
@@ -3,53 +3,37 @@
 package tmux
 
 import (
-	"claude-squad/log"
-	"os"
 	"time"
-
-	"golang.org/x/term"
 )
 
 // monitorWindowSize monitors and handles window resize events while attached.
 func (t *TmuxSession) monitorWindowSize() {
-	// Use the current terminal height and width.
-	doUpdate := func() {
-		cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
-		if err != nil {
-			log.ErrorLog.Printf("failed to update window size: %v", err)
-		} else {
-			if err := t.updateWindowSize(cols, rows); err != nil {
-				log.ErrorLog.Printf("failed to update window size: %v", err)
-			}
-		}
-	}
-
-	// Do one at the start to set the initial size
-	doUpdate()
-
 	// On Windows, we'll just periodically check for window size changes
 	// since SIGWINCH is not available
 	ticker := time.NewTicker(250 * time.Millisecond)
-	defer ticker.Stop()
 
 	var lastCols, lastRows int
-	lastCols, lastRows, _ = term.GetSize(int(os.Stdin.Fd()))
+	lastCols, lastRows, _ = terminalSize()
 
-	t.wg.Add(1)
+	// Detach clears t.ctx once the goroutines are done, so hold on to our own reference.
+	ctx := t.ctx
+	t.monitorWg.Add(1)
 	go func() {
-		defer t.wg.Done()
+		defer t.monitorWg.Done()
+		// Stop the ticker when the goroutine exits rather than when this function returns.
+		defer ticker.Stop()
 		for {
 			select {
-			case <-t.ctx.Done():
+			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
+				cols, rows, err := terminalSize()
 				if err != nil {
 					continue
 				}
 				if cols != lastCols || rows != lastRows {
 					lastCols, lastRows = cols, rows
-					doUpdate()
+					t.resizeToTerminal()
 				}
 			}
 		}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package tmux
+
+import "testing"
+
+// raiseWinch does nothing, as monitorWindowSize polls the terminal size on Windows.
+func raiseWinch(*testing.T) {}
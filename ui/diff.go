@@ -21,11 +21,30 @@ type DiffPane struct {
 	stats    string
 	width    int
 	height   int
+
+	// instance is the instance whose diff is currently displayed.
+	instance *session.Instance
+	// scrollOffsets remembers the scroll position of each instance's diff so switching back restores it.
+	scrollOffsets map[*session.Instance]int
+
+	// showFiles shows the list of changed files instead of the diff. Scrolling moves the selection in the list.
+	showFiles bool
+	files     []string
+	fileIdx   int
+	// filesErr is shown instead of the list if the files couldn't be listed.
+	filesErr error
+	// filesFor and filesDiff are the instance and diff content the list was built for. It's rebuilt when either
+	// changes.
+	filesFor  *session.Instance
+	filesDiff string
+	// content is the raw diff content, used to find the file to jump to when leaving the list.
+	content string
 }
 
 func NewDiffPane() *DiffPane {
 	return &DiffPane{
-		viewport: viewport.New(0, 0),
+		viewport:      viewport.New(0, 0),
+		scrollOffsets: make(map[*session.Instance]int),
 	}
 }
 
@@ -35,12 +54,26 @@ func (d *DiffPane) SetSize(width, height int) {
 	d.viewport.Width = width
 	d.viewport.Height = height
 	// Update viewport content if diff exists
-	if d.diff != "" || d.stats != "" {
+	if d.showFiles && d.filesFor != nil {
+		d.viewport.SetContent(d.renderFiles())
+	} else if d.diff != "" || d.stats != "" {
 		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
 	}
 }
 
 func (d *DiffPane) SetDiff(instance *session.Instance) error {
+	if instance != d.instance {
+		// Save the offset of the instance we're switching away from and restore the offset of the new one
+		// once its content is set. SetYOffset clamps the offset if the diff shrank.
+		if d.instance != nil {
+			d.scrollOffsets[d.instance] = d.viewport.YOffset
+		}
+		d.instance = instance
+		defer func() {
+			d.viewport.SetYOffset(d.scrollOffsets[instance])
+		}()
+	}
+
 	centeredFallbackMessage := lipgloss.Place(
 		d.width,
 		d.height,
@@ -84,29 +117,164 @@ func (d *DiffPane) SetDiff(instance *session.Instance) error {
 	if stats.IsEmpty() {
 		d.stats = ""
 		d.diff = ""
+		d.content = ""
+		if instance.DiffFilter != "" {
+			centeredFallbackMessage = lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center,
+				fmt.Sprintf("No changes matching %s", instance.DiffFilter))
+		} else if instance.DiffBaseline != "" {
+			centeredFallbackMessage = lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center,
+				"No changes since they were marked as seen")
+		}
 		d.viewport.SetContent(centeredFallbackMessage)
 	} else {
 		additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", stats.Added))
 		deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", stats.Removed))
 		d.stats = lipgloss.JoinHorizontal(lipgloss.Center, additions, " ", deletions)
+		if instance.DiffFilter != "" {
+			d.stats += HunkStyle.Render(fmt.Sprintf("  filter: %s (%d+ %d- in all files)",
+				instance.DiffFilter, stats.TotalAdded, stats.TotalRemoved))
+		}
+		if instance.DiffBaseline != "" {
+			d.stats += HunkStyle.Render("  since marked as seen")
+		}
 		d.diff = colorizeDiff(stats.Content)
-		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
+		d.content = stats.Content
+		if d.showFiles {
+			d.updateFiles(instance)
+			d.viewport.SetContent(d.renderFiles())
+			d.scrollToSelectedFile()
+		} else {
+			d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
+		}
 	}
 
 	return nil
 }
 
+// ToggleFiles switches between the diff and the list of changed files. Leaving the list scrolls the diff to the
+// selected file.
+func (d *DiffPane) ToggleFiles() {
+	d.showFiles = !d.showFiles
+	if d.showFiles {
+		// Rebuild the list on the next SetDiff.
+		d.filesFor = nil
+		d.fileIdx = 0
+		return
+	}
+
+	d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
+	if d.fileIdx >= len(d.files) {
+		return
+	}
+	_, path, _ := strings.Cut(d.files[d.fileIdx], "\t")
+	header := fmt.Sprintf("diff --git a/%s b/%s", path, path)
+	for idx, line := range strings.Split(d.content, "\n") {
+		if line == header {
+			// The stats take up the first line.
+			d.viewport.SetYOffset(idx + 1)
+			return
+		}
+	}
+}
+
+// IsShowingFiles returns whether the list of changed files is shown instead of the diff
+func (d *DiffPane) IsShowingFiles() bool {
+	return d.showFiles
+}
+
+// updateFiles rebuilds the list of changed files if the instance or its diff changed.
+func (d *DiffPane) updateFiles(instance *session.Instance) {
+	if d.filesFor == instance && d.filesDiff == d.content {
+		return
+	}
+	if d.filesFor != instance {
+		d.fileIdx = 0
+	}
+	d.filesFor = instance
+	d.filesDiff = d.content
+	d.files, d.filesErr = instance.ChangedFiles()
+	d.fileIdx = max(min(d.fileIdx, len(d.files)-1), 0)
+}
+
+func (d *DiffPane) renderFiles() string {
+	if d.filesErr != nil {
+		return lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center, fmt.Sprintf("Error: %v", d.filesErr))
+	}
+
+	lines := []string{d.stats}
+	for idx, file := range d.files {
+		changeType, path, _ := strings.Cut(file, "\t")
+		switch changeType {
+		case "A":
+			changeType = AdditionStyle.Render(changeType)
+		case "D":
+			changeType = DeletionStyle.Render(changeType)
+		default:
+			changeType = HunkStyle.Render(changeType)
+		}
+		cursor := "  "
+		if idx == d.fileIdx {
+			cursor = "> "
+			path = lipgloss.NewStyle().Bold(true).Render(path)
+		}
+		lines = append(lines, cursor+changeType+" "+path)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scrollToSelectedFile scrolls the list of changed files so the selected file is visible.
+func (d *DiffPane) scrollToSelectedFile() {
+	// The stats take up the first line.
+	line := d.fileIdx + 1
+	if line < d.viewport.YOffset {
+		d.viewport.SetYOffset(line)
+	} else if line >= d.viewport.YOffset+d.height {
+		d.viewport.SetYOffset(line - d.height + 1)
+	}
+}
+
 func (d *DiffPane) String() string {
 	return d.viewport.View()
 }
 
-// ScrollUp scrolls the viewport up
+// JumpToLine scrolls so line, counting from 1, is at the top, or selects the file at that position in the list of
+// changed files.
+func (d *DiffPane) JumpToLine(line int) {
+	if d.showFiles {
+		d.fileIdx = max(min(line-1, len(d.files)-1), 0)
+		d.scrollToSelectedFile()
+		return
+	}
+	d.viewport.SetYOffset(line - 1)
+}
+
+// JumpToPercent scrolls percent of the way through the diff, ex. 0 to the top and 100 to the bottom, or selects
+// the file that far through the list of changed files.
+func (d *DiffPane) JumpToPercent(percent int) {
+	percent = min(max(percent, 0), 100)
+	if d.showFiles {
+		d.fileIdx = max((len(d.files)-1)*percent/100, 0)
+		d.scrollToSelectedFile()
+		return
+	}
+	d.viewport.SetYOffset((d.viewport.TotalLineCount() - d.viewport.Height) * percent / 100)
+}
+
+// ScrollUp scrolls the viewport up, or selects the previous file in the list of changed files
 func (d *DiffPane) ScrollUp() {
+	if d.showFiles {
+		d.fileIdx = max(d.fileIdx-1, 0)
+		return
+	}
 	d.viewport.LineUp(1)
 }
 
-// ScrollDown scrolls the viewport down
+// ScrollDown scrolls the viewport down, or selects the next file in the list of changed files
 func (d *DiffPane) ScrollDown() {
+	if d.showFiles {
+		d.fileIdx = max(min(d.fileIdx+1, len(d.files)-1), 0)
+		return
+	}
 	d.viewport.LineDown(1)
 }
 
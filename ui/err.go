@@ -7,6 +7,8 @@ import (
 type ErrBox struct {
 	height, width int
 	err           error
+	// info is a non-error message, such as a confirmation. It's only shown if there's no error.
+	info string
 }
 
 var errStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
@@ -14,6 +16,11 @@ var errStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
 	Dark:  "#FF0000",
 })
 
+var infoStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
+	Light: "#51bd73",
+	Dark:  "#51bd73",
+})
+
 func NewErrBox() *ErrBox {
 	return &ErrBox{}
 }
@@ -22,8 +29,14 @@ func (e *ErrBox) SetError(err error) {
 	e.err = err
 }
 
+// SetInfo sets a non-error message to display.
+func (e *ErrBox) SetInfo(info string) {
+	e.info = info
+}
+
 func (e *ErrBox) Clear() {
 	e.err = nil
+	e.info = ""
 }
 
 func (e *ErrBox) SetSize(width, height int) {
@@ -32,6 +45,9 @@ func (e *ErrBox) SetSize(width, height int) {
 }
 
 func (e *ErrBox) String() string {
+	if e.err == nil && e.info != "" {
+		return lipgloss.Place(e.width, e.height, lipgloss.Center, lipgloss.Center, infoStyle.Render(e.info))
+	}
 	var err string
 	if e.err != nil {
 		err = e.err.Error()
@@ -5,18 +5,27 @@ import (
 	"claude-squad/session"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 const readyIcon = "● "
 const pausedIcon = "⏸ "
+const pinnedIcon = "★ "
+const autoYesIcon = "» "
 
 var readyStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
 
+var runningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Light: "#c9a227", Dark: "#e5c07b"})
+
 var addedLinesStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
 
@@ -52,12 +61,61 @@ var autoYesStyle = lipgloss.NewStyle().
 	Background(lipgloss.Color("#dde4f0")).
 	Foreground(lipgloss.Color("#1a1a1a"))
 
+// SortMode is the order instances are listed in. Pinned instances always come first.
+type SortMode string
+
+const (
+	// SortCreated lists instances in the order they were created.
+	SortCreated SortMode = "created"
+	// SortTitle lists instances alphabetically by title.
+	SortTitle SortMode = "title"
+	// SortStatus lists running instances first, then ready, loading and paused ones.
+	SortStatus SortMode = "status"
+	// SortRecent lists the most recently active instances first.
+	SortRecent SortMode = "recent"
+	// SortManual keeps the order the instances were arranged in with MoveSelected.
+	SortManual SortMode = "manual"
+)
+
+// sortModes is the order SortMode.Next cycles through.
+var sortModes = []SortMode{SortCreated, SortTitle, SortStatus, SortRecent, SortManual}
+
+// ParseSortMode parses a sort mode name. An empty name is SortCreated.
+func ParseSortMode(s string) (SortMode, error) {
+	if s == "" {
+		return SortCreated, nil
+	}
+	for _, mode := range sortModes {
+		if string(mode) == strings.ToLower(s) {
+			return mode, nil
+		}
+	}
+	return SortCreated, fmt.Errorf("invalid sort mode %q: must be created, title, status, recent or manual", s)
+}
+
+// Next returns the sort mode after this one.
+func (s SortMode) Next() SortMode {
+	for idx, mode := range sortModes {
+		if mode == s {
+			return sortModes[(idx+1)%len(sortModes)]
+		}
+	}
+	return SortCreated
+}
+
 type List struct {
 	items         []*session.Instance
 	selectedIdx   int
 	height, width int
 	renderer      *InstanceRenderer
 	autoyes       bool
+	sortMode      SortMode
+	// hidePaused hides paused instances from the list. They're still in items, and the selection skips them.
+	hidePaused bool
+	// workspace is the name of the workspace shown, and workspaceTitles the titles of its instances. The others
+	// are hidden. workspaceTitles is nil if every instance is shown.
+	workspace       string
+	workspaceTitles map[string]bool
 
 	// map of repo name to number of instances using it. Used to display the repo name only if there are
 	// multiple repos in play.
@@ -70,6 +128,7 @@ func NewList(spinner *spinner.Model, autoYes bool) *List {
 		renderer: &InstanceRenderer{spinner: spinner},
 		repos:    make(map[string]int),
 		autoyes:  autoYes,
+		sortMode: SortCreated,
 	}
 }
 
@@ -100,6 +159,82 @@ func (l *List) NumInstances() int {
 	return len(l.items)
 }
 
+// NumVisible returns the number of instances which aren't hidden.
+func (l *List) NumVisible() int {
+	visible := 0
+	for _, item := range l.items {
+		if !l.hidden(item) {
+			visible++
+		}
+	}
+	return visible
+}
+
+// SetHidePaused hides or shows the paused instances. If the selected instance gets hidden, the nearest visible
+// one is selected instead.
+func (l *List) SetHidePaused(hide bool) {
+	l.hidePaused = hide
+	l.selectVisible()
+}
+
+// HidePaused returns whether paused instances are hidden.
+func (l *List) HidePaused() bool {
+	return l.hidePaused
+}
+
+// SetWorkspace only shows the started instances with one of the titles, under the name of their workspace. An
+// empty name shows every instance.
+func (l *List) SetWorkspace(name string, titles []string) {
+	l.workspace = name
+	l.workspaceTitles = nil
+	if name != "" {
+		l.workspaceTitles = make(map[string]bool, len(titles))
+		for _, title := range titles {
+			l.workspaceTitles[title] = true
+		}
+	}
+	l.selectVisible()
+}
+
+// InWorkspace returns whether instance is in the workspace shown. Every instance is if there's none.
+func (l *List) InWorkspace(instance *session.Instance) bool {
+	// Instances which are still being named belong wherever they're created.
+	return l.workspaceTitles == nil || !instance.Started() || l.workspaceTitles[instance.Title]
+}
+
+// hidden returns whether instance is hidden from the list.
+func (l *List) hidden(instance *session.Instance) bool {
+	return (l.hidePaused && instance.Paused()) || !l.InWorkspace(instance)
+}
+
+// nextVisible returns the index of the first visible instance from idx onwards in the direction of step, or -1 if
+// there's none.
+func (l *List) nextVisible(idx, step int) int {
+	for ; idx >= 0 && idx < len(l.items); idx += step {
+		if !l.hidden(l.items[idx]) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// selectVisible moves the selection to the nearest visible instance, preferring the ones below, if the selected
+// one is hidden. The selection stays put if every instance is hidden.
+func (l *List) selectVisible() {
+	if len(l.items) == 0 {
+		return
+	}
+	l.selectedIdx = min(l.selectedIdx, len(l.items)-1)
+	if !l.hidden(l.items[l.selectedIdx]) {
+		return
+	}
+	if idx := l.nextVisible(l.selectedIdx, 1); idx >= 0 {
+		l.selectedIdx = idx
+	} else if idx := l.nextVisible(l.selectedIdx, -1); idx >= 0 {
+		l.selectedIdx = idx
+	}
+}
+
 // InstanceRenderer handles rendering of session.Instance objects
 type InstanceRenderer struct {
 	spinner *spinner.Model
@@ -128,8 +263,8 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	// add spinner next to title if it's running
 	var join string
 	switch i.Status {
-	case session.Running:
-		join = fmt.Sprintf("%s ", r.spinner.View())
+	case session.Running, session.Loading:
+		join = runningStyle.Render(fmt.Sprintf("%s ", r.spinner.View()))
 	case session.Ready:
 		join = readyStyle.Render(readyIcon)
 	case session.Paused:
@@ -139,9 +274,18 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 
 	// Cut the title if it's too long
 	titleText := i.Title
+	if i.AutoYes {
+		titleText = autoYesIcon + titleText
+	}
+	if i.Pinned {
+		titleText = pinnedIcon + titleText
+	}
+	if len(i.Tags) > 0 {
+		titleText += fmt.Sprintf(" [%s]", strings.Join(i.Tags, ", "))
+	}
 	widthAvail := r.width - 3 - len(prefix) - 1
-	if widthAvail > 0 && widthAvail < len(titleText) && len(titleText) >= widthAvail-3 {
-		titleText = titleText[:widthAvail-3] + "..."
+	if widthAvail > 0 {
+		titleText = ansi.Truncate(titleText, widthAvail, "…")
 	}
 	title := titleS.Render(lipgloss.JoinHorizontal(
 		lipgloss.Left,
@@ -178,11 +322,28 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	if diffWidth > 0 {
 		diffWidth += 1
 	}
+	// Drop the diff stats if the list is too narrow to fit them, rather than overflowing the column.
+	if diffWidth > remainingWidth {
+		diff = ""
+		diffWidth = 0
+	}
 
 	// Use fixed width for diff stats to avoid layout issues
 	remainingWidth -= diffWidth
 
-	branch := i.Branch
+	// The age goes before the diff stats. Drop it if it would leave too little room for the branch name.
+	const minBranchWidth = 10
+	// Show what's checked out in the worktree, in case it was switched from inside the session.
+	branch := i.Head()
+	age := formatAge(i.CreatedAt)
+	if age != "" {
+		age += " "
+	}
+	if remainingWidth-len(age) < min(len(branch), minBranchWidth) {
+		age = ""
+	}
+	remainingWidth -= len(age)
+
 	if i.Started() && hasMultipleRepos {
 		repoName, err := i.RepoName()
 		if err != nil {
@@ -210,7 +371,7 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 		spaces = strings.Repeat(" ", remainingWidth)
 	}
 
-	branchLine := fmt.Sprintf("%s %s-%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, diff)
+	branchLine := fmt.Sprintf("%s %s-%s%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, age, diff)
 
 	// join title and subtitle
 	text := lipgloss.JoinVertical(
@@ -222,9 +383,34 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	return text
 }
 
+// formatAge returns how long ago t was, ex. "2h ago". It returns "" for the zero time.
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 func (l *List) String() string {
-	const titleText = " Instances "
+	titleText := "Instances"
 	const autoYesText = " auto-yes "
+	if l.workspace != "" {
+		titleText += " [" + l.workspace + "]"
+	}
+	if visible := l.NumVisible(); visible < len(l.items) {
+		titleText += fmt.Sprintf(" %d/%d", visible, len(l.items))
+	}
+	titleText = " " + titleText + " "
 
 	// Write the title.
 	var b strings.Builder
@@ -249,12 +435,17 @@ func (l *List) String() string {
 	b.WriteString("\n")
 	b.WriteString("\n")
 
-	// Render the list.
+	// Render the list. Hidden instances are skipped and the rest are numbered by their position in the list.
+	num := 0
 	for i, item := range l.items {
-		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1))
-		if i != len(l.items)-1 {
+		if l.hidden(item) {
+			continue
+		}
+		if num > 0 {
 			b.WriteString("\n\n")
 		}
+		num++
+		b.WriteString(l.renderer.Render(item, num, i == l.selectedIdx, len(l.repos) > 1))
 	}
 	return lipgloss.Place(l.width, l.height, lipgloss.Left, lipgloss.Top, b.String())
 }
@@ -264,8 +455,8 @@ func (l *List) Down() {
 	if len(l.items) == 0 {
 		return
 	}
-	if l.selectedIdx < len(l.items)-1 {
-		l.selectedIdx++
+	if idx := l.nextVisible(l.selectedIdx+1, 1); idx >= 0 {
+		l.selectedIdx = idx
 	}
 }
 
@@ -275,6 +466,8 @@ func (l *List) Kill() {
 		return
 	}
 	targetInstance := l.items[l.selectedIdx]
+	// Hidden instances shouldn't be selected again once this one is gone.
+	defer l.selectVisible()
 
 	// Kill the tmux session
 	if err := targetInstance.Kill(); err != nil {
@@ -298,18 +491,43 @@ func (l *List) Kill() {
 	l.items = append(l.items[:l.selectedIdx], l.items[l.selectedIdx+1:]...)
 }
 
+// Remove removes instance from the list without killing it. The selection stays on the same instance if it's not
+// the one removed.
+func (l *List) Remove(instance *session.Instance) {
+	idx := slices.Index(l.items, instance)
+	if idx < 0 {
+		return
+	}
+
+	if repoName, err := instance.RepoName(); err == nil {
+		l.rmRepo(repoName)
+	}
+
+	l.items = slices.Delete(l.items, idx, idx+1)
+	if idx < l.selectedIdx || l.selectedIdx >= len(l.items) {
+		l.selectedIdx = max(l.selectedIdx-1, 0)
+	}
+	l.selectVisible()
+}
+
 func (l *List) Attach() (chan struct{}, error) {
 	targetInstance := l.items[l.selectedIdx]
 	return targetInstance.Attach()
 }
 
+// AttachReadOnly attaches to the selected instance without forwarding input to it.
+func (l *List) AttachReadOnly() (chan struct{}, error) {
+	targetInstance := l.items[l.selectedIdx]
+	return targetInstance.AttachReadOnly()
+}
+
 // Up selects the prev item in the list.
 func (l *List) Up() {
 	if len(l.items) == 0 {
 		return
 	}
-	if l.selectedIdx > 0 {
-		l.selectedIdx--
+	if idx := l.nextVisible(l.selectedIdx-1, -1); idx >= 0 {
+		l.selectedIdx = idx
 	}
 }
 
@@ -336,8 +554,11 @@ func (l *List) rmRepo(repo string) {
 // When creating a new one and entering the name, you want to call the finalizer once the name is done.
 func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
 	l.items = append(l.items, instance)
-	// The finalizer registers the repo name once the instance is started.
+	// Instances which aren't started yet stay at the end while they're being named.
+	l.Sort()
+	// The finalizer registers the repo name and sorts the instance into place once it is started.
 	return func() {
+		l.Sort()
 		repoName, err := instance.RepoName()
 		if err != nil {
 			log.ErrorLog.Printf("could not get repo name: %v", err)
@@ -348,20 +569,103 @@ func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
 	}
 }
 
-// GetSelectedInstance returns the currently selected instance
-func (l *List) GetSelectedInstance() *session.Instance {
+// TogglePinSelected pins or unpins the selected instance. It stays selected as it moves.
+func (l *List) TogglePinSelected() {
 	if len(l.items) == 0 {
+		return
+	}
+	l.items[l.selectedIdx].Pinned = !l.items[l.selectedIdx].Pinned
+	l.Sort()
+}
+
+// MoveSelected moves the selected instance one place up (delta -1) or down (delta 1) the list and switches to
+// SortManual so it stays there. It stays selected. Instances only move among the ones with the same pinned state.
+// Returns false if the instance couldn't move.
+func (l *List) MoveSelected(delta int) bool {
+	if len(l.items) == 0 {
+		return false
+	}
+	// Skip over hidden instances, so the instance moves past the next one which is shown.
+	target := l.nextVisible(l.selectedIdx+delta, delta)
+	if target < 0 {
+		return false
+	}
+	selected, other := l.items[l.selectedIdx], l.items[target]
+	if !selected.Started() || !other.Started() || selected.Pinned != other.Pinned {
+		return false
+	}
+	l.items[l.selectedIdx], l.items[target] = other, selected
+	l.selectedIdx = target
+	l.sortMode = SortManual
+	return true
+}
+
+// SetSortMode sets the order instances are listed in and re-sorts the list.
+func (l *List) SetSortMode(mode SortMode) {
+	l.sortMode = mode
+	l.Sort()
+}
+
+// GetSortMode returns the order instances are listed in.
+func (l *List) GetSortMode() SortMode {
+	return l.sortMode
+}
+
+// Sort orders the instances by the sort mode, with pinned instances at the top. Instances which haven't been
+// started yet are kept at the end, since the instance being created is expected to be the last one. The sort is
+// stable and the selected instance stays selected.
+func (l *List) Sort() {
+	var selected *session.Instance
+	if len(l.items) > 0 {
+		selected = l.items[l.selectedIdx]
+	}
+	sort.SliceStable(l.items, func(a, b int) bool {
+		x, y := l.items[a], l.items[b]
+		if x.Started() != y.Started() {
+			return x.Started()
+		}
+		if x.Pinned != y.Pinned {
+			return x.Pinned
+		}
+		switch l.sortMode {
+		case SortTitle:
+			return strings.ToLower(x.Title) < strings.ToLower(y.Title)
+		case SortStatus:
+			return x.Status < y.Status
+		case SortRecent:
+			return x.UpdatedAt.After(y.UpdatedAt)
+		case SortManual:
+			return false
+		default:
+			return x.CreatedAt.Before(y.CreatedAt)
+		}
+	})
+	for idx, item := range l.items {
+		if item == selected {
+			l.selectedIdx = idx
+			break
+		}
+	}
+	// The selected instance may have been paused since it was selected.
+	l.selectVisible()
+}
+
+// GetSelectedInstance returns the currently selected instance, or nil if every instance is hidden.
+func (l *List) GetSelectedInstance() *session.Instance {
+	if len(l.items) == 0 || l.hidden(l.items[l.selectedIdx]) {
 		return nil
 	}
 	return l.items[l.selectedIdx]
 }
 
-// SetSelectedInstance sets the selected index. Noop if the index is out of bounds.
+// SetSelectedInstance sets the selected index. Noop if the index is out of bounds. If the instance is hidden, the
+// nearest visible one is selected.
 func (l *List) SetSelectedInstance(idx int) {
 	if idx >= len(l.items) {
 		return
 	}
 	l.selectedIdx = idx
+	l.selectVisible()
 }
 
 // GetInstances returns all instances in the list
@@ -46,7 +46,7 @@ type Menu struct {
 	height, width int
 	state         MenuState
 	instance      *session.Instance
-	isInDiffTab   bool
+	activeTab     int
 
 	// keyDown is the key which is pressed. The default is -1.
 	keyDown keys.KeyName
@@ -58,10 +58,10 @@ var promptMenuOptions = []keys.KeyName{keys.KeyEnter}
 
 func NewMenu() *Menu {
 	return &Menu{
-		options:     defaultMenuOptions,
-		state:       StateDefault,
-		isInDiffTab: false,
-		keyDown:     -1,
+		options:   defaultMenuOptions,
+		state:     StateDefault,
+		activeTab: PreviewTab,
+		keyDown:   -1,
 	}
 }
 
@@ -87,9 +87,9 @@ func (m *Menu) SetInstance(instance *session.Instance) {
 	}
 }
 
-// SetInDiffTab updates whether we're currently in the diff tab
-func (m *Menu) SetInDiffTab(inDiffTab bool) {
-	m.isInDiffTab = inDiffTab
+// SetActiveTab updates which tab of the tabbed window is active, ex. DiffTab.
+func (m *Menu) SetActiveTab(tab int) {
+	m.activeTab = tab
 	if m.state == StateDefault {
 		m.updateOptions()
 	}
@@ -123,7 +123,7 @@ func (m *Menu) addInstanceOptions() {
 	}
 
 	// Navigation group (when in diff tab)
-	if m.isInDiffTab {
+	if m.activeTab == DiffTab {
 		actionGroup = append(actionGroup, keys.KeyShiftUp)
 	}
 
@@ -158,6 +158,11 @@ func (m *Menu) String() string {
 
 	for i, k := range m.options {
 		binding := keys.GlobalkeyBindings[k]
+		desc := binding.Help().Desc
+		if k == keys.KeyTab {
+			// Name the tab the key switches to, which also shows which one is active.
+			desc = "to " + strings.ToLower(tabNames[(m.activeTab+1)%len(tabNames)])
+		}
 
 		var (
 			localActionStyle = actionGroupStyle
@@ -176,11 +181,11 @@ func (m *Menu) String() string {
 		if inActionGroup {
 			s.WriteString(localActionStyle.Render(binding.Help().Key))
 			s.WriteString(" ")
-			s.WriteString(localActionStyle.Render(binding.Help().Desc))
+			s.WriteString(localActionStyle.Render(desc))
 		} else {
 			s.WriteString(localKeyStyle.Render(binding.Help().Key))
 			s.WriteString(" ")
-			s.WriteString(localDescStyle.Render(binding.Help().Desc))
+			s.WriteString(localDescStyle.Render(desc))
 		}
 
 		// Add appropriate separator
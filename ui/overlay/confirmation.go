@@ -0,0 +1,62 @@
+package overlay
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ConfirmationOverlay asks the user a yes/no question
+type ConfirmationOverlay struct {
+	// Message is the question shown to the user
+	Message   string
+	Confirmed bool
+}
+
+// NewConfirmationOverlay creates a new confirmation overlay with the given message
+func NewConfirmationOverlay(message string) *ConfirmationOverlay {
+	return &ConfirmationOverlay{
+		Message: message,
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly. 'y' confirms, while 'n' and esc
+// cancel. Returns true if the overlay should be closed
+func (c *ConfirmationOverlay) HandleKeyPress(key tea.KeyMsg) bool {
+	switch key.String() {
+	case "y", "Y":
+		c.Confirmed = true
+		return true
+	case "n", "N", "esc", "ctrl+c":
+		c.Confirmed = false
+		return true
+	default:
+		return false
+	}
+}
+
+// IsConfirmed returns whether the user confirmed
+func (c *ConfirmationOverlay) IsConfirmed() bool {
+	return c.Confirmed
+}
+
+// Render renders the confirmation overlay
+func (c *ConfirmationOverlay) Render(opts ...WhitespaceOption) string {
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#de613e")).
+		Padding(1, 2)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#AAAAAA")).
+		MarginTop(1)
+
+	content := boxStyle.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		c.Message,
+		hintStyle.Render("Press y to confirm, n or esc to cancel"),
+	))
+
+	return PlaceOverlay(0, 0, content, strings.Repeat("\n", lipgloss.Height(content)), true, true, opts...)
+}
@@ -0,0 +1,94 @@
+package overlay
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxSelectionItemWidth is the width long items are cut to so the overlay fits on screen
+const maxSelectionItemWidth = 80
+
+// SelectionOverlay lets the user pick one item from a list
+type SelectionOverlay struct {
+	// Title is shown above the items
+	Title    string
+	Items    []string
+	Selected int
+	// Chosen is true if the user picked an item rather than canceling
+	Chosen bool
+}
+
+// NewSelectionOverlay creates a new selection overlay with the given items. The first item is selected.
+func NewSelectionOverlay(title string, items []string) *SelectionOverlay {
+	return &SelectionOverlay{
+		Title: title,
+		Items: items,
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly. Up and down move the selection, enter
+// picks the selected item and esc cancels. Returns true if the overlay should be closed
+func (s *SelectionOverlay) HandleKeyPress(key tea.KeyMsg) bool {
+	switch key.String() {
+	case "up", "k":
+		if s.Selected > 0 {
+			s.Selected--
+		}
+	case "down", "j":
+		if s.Selected < len(s.Items)-1 {
+			s.Selected++
+		}
+	case "enter":
+		s.Chosen = len(s.Items) > 0
+		return true
+	case "esc", "ctrl+c", "q":
+		s.Chosen = false
+		return true
+	}
+	return false
+}
+
+// IsChosen returns whether the user picked an item
+func (s *SelectionOverlay) IsChosen() bool {
+	return s.Chosen
+}
+
+// Render renders the selection overlay
+func (s *SelectionOverlay) Render(opts ...WhitespaceOption) string {
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("62")).
+		Bold(true).
+		MarginBottom(1)
+
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("#dde4f0")).
+		Foreground(lipgloss.Color("#1a1a1a"))
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#AAAAAA")).
+		MarginTop(1)
+
+	lines := []string{titleStyle.Render(s.Title)}
+	for idx, item := range s.Items {
+		// Only show the first line of multiline items
+		item, _, _ = strings.Cut(item, "\n")
+		if len(item) > maxSelectionItemWidth {
+			item = item[:maxSelectionItemWidth-3] + "..."
+		}
+		if idx == s.Selected {
+			item = selectedStyle.Render(item)
+		}
+		lines = append(lines, item)
+	}
+	lines = append(lines, hintStyle.Render("↑/↓ to move, enter to select, esc to cancel"))
+
+	content := boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	return PlaceOverlay(0, 0, content, strings.Repeat("\n", lipgloss.Height(content)), true, true, opts...)
+}
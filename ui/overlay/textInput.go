@@ -43,7 +43,8 @@ func (t *TextInputOverlay) HandleKeyPress(key tea.KeyMsg) bool {
 		t.FocusIndex = (t.FocusIndex + 1) % 2
 		return false
 	case tea.KeyEnter:
-		if t.FocusIndex == 1 {
+		// Single line inputs submit on enter since there's nothing else for enter to do.
+		if t.FocusIndex == 1 || !t.Multiline {
 			// Enter button is focused, submit the form
 			t.Submitted = true
 			if t.OnSubmit != nil {
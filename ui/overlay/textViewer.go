@@ -0,0 +1,71 @@
+package overlay
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// TextViewerOverlay shows read-only text, such as the log
+type TextViewerOverlay struct {
+	// Title is shown above the text
+	Title   string
+	Content string
+	// Width is the maximum width of a line. Longer lines are cut.
+	Width int
+}
+
+// NewTextViewerOverlay creates a new text viewer overlay with the given title and content
+func NewTextViewerOverlay(title, content string, width int) *TextViewerOverlay {
+	return &TextViewerOverlay{
+		Title:   title,
+		Content: content,
+		Width:   width,
+	}
+}
+
+// SetContent replaces the text which is shown
+func (t *TextViewerOverlay) SetContent(content string) {
+	t.Content = content
+}
+
+// HandleKeyPress processes a key press. esc or q close the overlay. Returns true if the overlay should be closed
+func (t *TextViewerOverlay) HandleKeyPress(key tea.KeyMsg) bool {
+	switch key.String() {
+	case "esc", "q", "ctrl+c":
+		return true
+	}
+	return false
+}
+
+// Render renders the text viewer overlay
+func (t *TextViewerOverlay) Render(opts ...WhitespaceOption) string {
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("62")).
+		Bold(true).
+		MarginBottom(1)
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#AAAAAA")).
+		MarginTop(1)
+
+	lines := strings.Split(t.Content, "\n")
+	for idx, line := range lines {
+		lines[idx] = ansi.Truncate(line, t.Width, "…")
+	}
+
+	content := boxStyle.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		titleStyle.Render(t.Title),
+		strings.Join(lines, "\n"),
+		hintStyle.Render("Press esc or q to close"),
+	))
+	return PlaceOverlay(0, 0, content, strings.Repeat("\n", lipgloss.Height(content)), true, true, opts...)
+}
@@ -6,16 +6,89 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 var previewPaneStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
 
+var descriptionStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#A0A0A0"}).
+	Italic(true)
+
+// maxDescriptionLines is the most lines of the instance's description shown above the preview.
+const maxDescriptionLines = 3
+
+var searchMatchStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("#FFD700")).
+	Foreground(lipgloss.Color("#1a1a1a"))
+
+var currentSearchMatchStyle = lipgloss.NewStyle().
+	Background(lipgloss.Color("#de613e")).
+	Foreground(lipgloss.Color("#1a1a1a"))
+
+// WrapMode is how the preview handles lines which are wider than the pane.
+type WrapMode string
+
+const (
+	// WrapModeWrap wraps long lines, at word boundaries where possible.
+	WrapModeWrap WrapMode = "wrap"
+	// WrapModeTruncate cuts long lines and marks them with an ellipsis.
+	WrapModeTruncate WrapMode = "truncate"
+	// WrapModeOff cuts long lines at the edge of the pane.
+	WrapModeOff WrapMode = "off"
+)
+
+// ParseWrapMode parses a wrap mode name. An empty name is WrapModeWrap.
+func ParseWrapMode(s string) (WrapMode, error) {
+	switch mode := WrapMode(strings.ToLower(s)); mode {
+	case "":
+		return WrapModeWrap, nil
+	case WrapModeWrap, WrapModeTruncate, WrapModeOff:
+		return mode, nil
+	default:
+		return WrapModeWrap, fmt.Errorf("invalid wrap mode %q: must be wrap, truncate or off", s)
+	}
+}
+
+// wrapBreakpoints are the characters other than spaces which long lines may be wrapped after, so file paths and
+// URLs wrap at a separator rather than mid-word.
+const wrapBreakpoints = "/-"
+
 type PreviewPane struct {
 	width  int
 	height int
 
 	previewState previewState
+	// description is the selected instance's description, shown above its output.
+	description string
+
+	// followMode keeps the preview pinned to the latest output of the pane. When it's disabled, the
+	// full scrollback is captured and can be paged through.
+	followMode bool
+	// scrollOffset is the first line of the scrollback which is displayed when not in follow mode. A
+	// negative value means the offset should be reset to show the tail of the scrollback.
+	scrollOffset int
+	// instance is the instance whose content is currently displayed.
+	instance *session.Instance
+
+	// searchTerm is the term highlighted in the scrollback. Empty if there's no search.
+	searchTerm string
+	// matches are the indexes of the lines containing the search term.
+	matches []int
+	// matchIdx is the index into matches of the current match.
+	matchIdx int
+	// jumpToMatch is set when the current match should be scrolled to on the next content update.
+	jumpToMatch bool
+	// jumpTarget is the line to scroll to on the next content update, once the full scrollback is captured, or a
+	// percentage of the way through it if jumpPercent is set. Negative if there's no jump.
+	jumpTarget  int
+	jumpPercent bool
+
+	// maxChars limits how much of the scrollback is kept. The oldest lines are dropped first. 0 means no limit.
+	maxChars int
+	// wrapMode is how lines wider than the pane are displayed.
+	wrapMode WrapMode
 }
 
 type previewState struct {
@@ -26,7 +99,207 @@ type previewState struct {
 }
 
 func NewPreviewPane() *PreviewPane {
-	return &PreviewPane{}
+	return &PreviewPane{followMode: true, wrapMode: WrapModeWrap, jumpTarget: -1}
+}
+
+// SetWrapMode sets how lines wider than the pane are displayed.
+func (p *PreviewPane) SetWrapMode(mode WrapMode) {
+	p.wrapMode = mode
+}
+
+// fitLines makes lines fit the width of the pane according to the wrap mode. The ansi helpers keep escape
+// sequences intact so colors aren't garbled.
+func (p *PreviewPane) fitLines(lines []string) []string {
+	fitted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		switch p.wrapMode {
+		case WrapModeTruncate:
+			fitted = append(fitted, ansi.Truncate(line, p.width, "…"))
+		case WrapModeOff:
+			fitted = append(fitted, ansi.Truncate(line, p.width, ""))
+		default:
+			fitted = append(fitted, strings.Split(ansi.Wrap(line, p.width, wrapBreakpoints), "\n")...)
+		}
+	}
+	return fitted
+}
+
+// SetMaxChars limits how many characters of the scrollback are kept. 0 means no limit.
+func (p *PreviewPane) SetMaxChars(maxChars int) {
+	p.maxChars = maxChars
+}
+
+// truncateScrollback drops the oldest lines of content so it fits in maxChars. It cuts on a line boundary so
+// escape sequences are never split.
+func truncateScrollback(content string, maxChars int) string {
+	if maxChars <= 0 || len(content) <= maxChars {
+		return content
+	}
+	tail := content[len(content)-maxChars:]
+	if idx := strings.IndexByte(tail, '\n'); idx >= 0 {
+		return tail[idx+1:]
+	}
+	// The last line alone is longer than the limit. Keep it whole rather than cutting it.
+	return content[strings.LastIndexByte(content, '\n')+1:]
+}
+
+// ToggleFollowMode switches between following the latest output and scrolling through the history.
+func (p *PreviewPane) ToggleFollowMode() {
+	p.followMode = !p.followMode
+	p.scrollOffset = -1
+}
+
+// IsFollowMode returns true if the preview is pinned to the latest output.
+func (p *PreviewPane) IsFollowMode() bool {
+	return p.followMode
+}
+
+// ScrollUp pages up through the scrollback. Noop in follow mode.
+func (p *PreviewPane) ScrollUp() {
+	if p.followMode {
+		return
+	}
+	p.clampScrollOffset()
+	p.scrollOffset = max(p.scrollOffset-p.pageSize(), 0)
+}
+
+// ScrollDown pages down through the scrollback. Noop in follow mode.
+func (p *PreviewPane) ScrollDown() {
+	if p.followMode {
+		return
+	}
+	p.clampScrollOffset()
+	p.scrollOffset += p.pageSize()
+	p.clampScrollOffset()
+}
+
+// JumpToLine scrolls the scrollback so line, counting from 1, is at the top. Lines past the end scroll to the
+// bottom. It leaves follow mode.
+func (p *PreviewPane) JumpToLine(line int) {
+	p.followMode = false
+	p.jumpTarget = max(line-1, 0)
+	p.jumpPercent = false
+}
+
+// JumpToPercent scrolls percent of the way through the scrollback, ex. 0 to the top and 100 to the bottom. It
+// leaves follow mode.
+func (p *PreviewPane) JumpToPercent(percent int) {
+	p.followMode = false
+	p.jumpTarget = min(max(percent, 0), 100)
+	p.jumpPercent = true
+}
+
+// applyJump scrolls to the pending jump, if there is one.
+func (p *PreviewPane) applyJump() {
+	if p.jumpTarget < 0 {
+		return
+	}
+	if p.jumpPercent {
+		p.scrollOffset = p.maxScrollOffset() * p.jumpTarget / 100
+	} else {
+		p.scrollOffset = p.jumpTarget
+	}
+	p.jumpTarget = -1
+	p.clampScrollOffset()
+}
+
+// Search highlights term in the full scrollback and scrolls to its last occurrence, which is usually
+// the most relevant one. An empty term clears the search.
+func (p *PreviewPane) Search(term string) {
+	p.searchTerm = term
+	p.matches = nil
+	if term == "" {
+		return
+	}
+	p.followMode = false
+	p.matchIdx = -1
+	p.jumpToMatch = true
+}
+
+// IsSearching returns true if there's an active search.
+func (p *PreviewPane) IsSearching() bool {
+	return p.searchTerm != ""
+}
+
+// NextMatch scrolls to the next match of the search term, wrapping around at the end.
+func (p *PreviewPane) NextMatch() {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.matchIdx = (p.matchIdx + 1) % len(p.matches)
+	p.scrollToMatch()
+}
+
+// PrevMatch scrolls to the previous match of the search term, wrapping around at the start.
+func (p *PreviewPane) PrevMatch() {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.matchIdx = (p.matchIdx - 1 + len(p.matches)) % len(p.matches)
+	p.scrollToMatch()
+}
+
+// SearchStatus returns a short description of the search for display, ex. "3/7 matches for 'foo'".
+func (p *PreviewPane) SearchStatus() string {
+	if len(p.matches) == 0 {
+		return fmt.Sprintf("no matches for '%s'", p.searchTerm)
+	}
+	return fmt.Sprintf("%d/%d matches for '%s'", p.matchIdx+1, len(p.matches), p.searchTerm)
+}
+
+// scrollToMatch scrolls so the current match is roughly in the middle of the pane.
+func (p *PreviewPane) scrollToMatch() {
+	p.scrollOffset = max(p.matches[p.matchIdx]-p.height/2, 0)
+	p.clampScrollOffset()
+}
+
+// updateMatches finds the lines of the current content which contain the search term.
+func (p *PreviewPane) updateMatches() {
+	p.matches = nil
+	if p.searchTerm == "" || p.previewState.fallback {
+		return
+	}
+	for idx, line := range strings.Split(p.previewState.text, "\n") {
+		if strings.Contains(ansi.Strip(line), p.searchTerm) {
+			p.matches = append(p.matches, idx)
+		}
+	}
+	if p.matchIdx >= len(p.matches) {
+		p.matchIdx = len(p.matches) - 1
+	}
+	if p.jumpToMatch && len(p.matches) > 0 {
+		p.jumpToMatch = false
+		p.matchIdx = len(p.matches) - 1
+		p.scrollToMatch()
+	}
+}
+
+// highlightMatches highlights the search term in a line. The line's own colors are dropped so they don't
+// clash with the highlighting.
+func (p *PreviewPane) highlightMatches(line string, current bool) string {
+	style := searchMatchStyle
+	if current {
+		style = currentSearchMatchStyle
+	}
+	return strings.ReplaceAll(ansi.Strip(line), p.searchTerm, style.Render(p.searchTerm))
+}
+
+// pageSize is the number of lines to move when paging through the scrollback.
+func (p *PreviewPane) pageSize() int {
+	return max(p.height-2, 1)
+}
+
+// clampScrollOffset keeps the scroll offset within the bounds of the current content. A negative offset
+// is moved to the tail.
+func (p *PreviewPane) clampScrollOffset() {
+	if maxOffset := p.maxScrollOffset(); p.scrollOffset < 0 || p.scrollOffset > maxOffset {
+		p.scrollOffset = maxOffset
+	}
+}
+
+// maxScrollOffset is the scroll offset which shows the tail of the current content.
+func (p *PreviewPane) maxScrollOffset() int {
+	return max(len(strings.Split(p.previewState.text, "\n"))-(p.height-1), 0)
 }
 
 func (p *PreviewPane) SetSize(width, maxHeight int) {
@@ -44,12 +317,23 @@ func (p *PreviewPane) setFallbackState(message string) {
 
 // Updates the preview pane content with the tmux pane content
 func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
+	if instance != p.instance {
+		// Start at the tail when switching to a different instance. The search belongs to the old instance.
+		p.instance = instance
+		p.scrollOffset = -1
+		p.Search("")
+	}
+	p.description = ""
+	if instance != nil {
+		p.description = instance.Description
+	}
+
 	switch {
 	case instance == nil:
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
 		return nil
 	case instance.Status == session.Paused:
-		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
+		paused := []string{
 			"Session is paused. Press 'r' to resume.",
 			"",
 			lipgloss.NewStyle().
@@ -58,17 +342,28 @@ func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
 					Dark:  "#FFD700",
 				}).
 				Render(fmt.Sprintf(
-					"The instance can be checked out at '%s' (copied to your clipboard)",
+					"The instance can be checked out at '%s'",
 					instance.Branch,
 				)),
-		))
+		}
+		if header := p.descriptionHeader(); len(header) > 0 {
+			paused = append(append(paused, ""), header...)
+		}
+		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center, paused...))
 		return nil
 	}
 
-	content, err := instance.Preview()
+	var content string
+	var err error
+	if p.followMode {
+		content, err = instance.Preview()
+	} else {
+		content, err = instance.PreviewFullHistory()
+	}
 	if err != nil {
 		return err
 	}
+	content = truncateScrollback(content, p.maxChars)
 
 	if len(content) == 0 {
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
@@ -79,6 +374,11 @@ func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
 		fallback: false,
 		text:     content,
 	}
+	if !p.followMode {
+		p.clampScrollOffset()
+		p.applyJump()
+	}
+	p.updateMatches()
 	return nil
 }
 
@@ -117,8 +417,32 @@ func (p *PreviewPane) String() string {
 
 	// Calculate available height accounting for border and margin
 	availableHeight := p.height - 1 //  1 for ellipsis
+	header := p.descriptionHeader()
+	if len(header) > 0 {
+		// Leave a blank line between the description and the output.
+		header = append(header, "")
+		availableHeight -= len(header)
+	}
 
 	lines := strings.Split(p.previewState.text, "\n")
+	if len(p.matches) > 0 {
+		lines = append([]string(nil), lines...)
+		for idx, lineIdx := range p.matches {
+			if lineIdx < len(lines) {
+				lines[lineIdx] = p.highlightMatches(lines[lineIdx], idx == p.matchIdx)
+			}
+		}
+	}
+	if !p.followMode && p.scrollOffset > 0 && p.scrollOffset < len(lines) {
+		lines = lines[p.scrollOffset:]
+	}
+	// Fit the lines to the width first so wrapped lines count towards the height.
+	lines = p.fitLines(lines)
+
+	// The history captured above the visible pane comes first, so keep the latest output in view.
+	if p.followMode && session.PreviewHistoryLines > 0 && availableHeight > 0 && len(lines) > availableHeight {
+		lines = lines[len(lines)-availableHeight:]
+	}
 
 	// Truncate if we have more lines than available height
 	if availableHeight > 0 {
@@ -132,6 +456,23 @@ func (p *PreviewPane) String() string {
 		}
 	}
 
-	content := strings.Join(lines, "\n")
+	content := strings.Join(append(header, lines...), "\n")
 	return previewPaneStyle.Width(p.width).Render(content)
 }
+
+// descriptionHeader returns the lines of the instance's description, wrapped to the width of the pane and cut
+// to maxDescriptionLines. It returns nil if there's no description.
+func (p *PreviewPane) descriptionHeader() []string {
+	if p.description == "" {
+		return nil
+	}
+	lines := strings.Split(ansi.Wrap(p.description, p.width, ""), "\n")
+	if len(lines) > maxDescriptionLines {
+		lines = lines[:maxDescriptionLines]
+		lines[maxDescriptionLines-1] = ansi.Truncate(lines[maxDescriptionLines-1], p.width-1, "") + "…"
+	}
+	for i, line := range lines {
+		lines[i] = descriptionStyle.Render(line)
+	}
+	return lines
+}
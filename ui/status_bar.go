@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var statusBarStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+
+var waitingStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#de613e"))
+
+// StatusBar is a one line summary of the statuses of all instances.
+type StatusBar struct {
+	width, height int
+
+	running, ready, paused, total int
+	// waiting is the number of instances waiting for the user to answer a prompt.
+	waiting int
+}
+
+func NewStatusBar() *StatusBar {
+	return &StatusBar{}
+}
+
+func (s *StatusBar) SetSize(width, height int) {
+	s.width = width
+	s.height = height
+}
+
+// Update recounts the instance statuses. waiting is the number of instances waiting for input.
+func (s *StatusBar) Update(instances []*session.Instance, waiting int) {
+	s.running, s.ready, s.paused = 0, 0, 0
+	s.total = len(instances)
+	s.waiting = waiting
+	for _, instance := range instances {
+		switch instance.Status {
+		case session.Running, session.Loading:
+			s.running++
+		case session.Ready:
+			s.ready++
+		case session.Paused:
+			s.paused++
+		}
+	}
+}
+
+func (s *StatusBar) String() string {
+	if s.total == 0 {
+		return lipgloss.Place(s.width, s.height, lipgloss.Center, lipgloss.Center, "")
+	}
+
+	noun := "sessions"
+	if s.total == 1 {
+		noun = "session"
+	}
+	counts := []string{
+		runningStyle.Render(fmt.Sprintf("%d running", s.running)),
+		readyStyle.Render(fmt.Sprintf("%d ready", s.ready)),
+		pausedStyle.Render(fmt.Sprintf("%d paused", s.paused)),
+	}
+	summary := statusBarStyle.Render(fmt.Sprintf("%d %s: ", s.total, noun)) +
+		strings.Join(counts, statusBarStyle.Render(", "))
+	if s.waiting > 0 {
+		summary += statusBarStyle.Render(" — ") + waitingStyle.Render(fmt.Sprintf("%d waiting for input", s.waiting))
+	}
+	return lipgloss.Place(s.width, s.height, lipgloss.Center, lipgloss.Center, summary)
+}
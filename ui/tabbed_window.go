@@ -35,6 +35,9 @@ const (
 	DiffTab
 )
 
+// tabNames are the names shown for the tabs, in the order of their indexes.
+var tabNames = []string{"Preview", "Diff"}
+
 type Tab struct {
 	Name   string
 	Render func(width int, height int) string
@@ -55,10 +58,7 @@ type TabbedWindow struct {
 
 func NewTabbedWindow(preview *PreviewPane, diff *DiffPane) *TabbedWindow {
 	return &TabbedWindow{
-		tabs: []string{
-			"Preview",
-			"Diff",
-		},
+		tabs:    tabNames,
 		preview: preview,
 		diff:    diff,
 	}
@@ -89,7 +89,23 @@ func (w *TabbedWindow) GetPreviewSize() (width, height int) {
 	return w.preview.width, w.preview.height
 }
 
-func (w *TabbedWindow) Toggle() {
+// GetActiveTab returns the index of the active tab.
+func (w *TabbedWindow) GetActiveTab() int {
+	return w.activeTab
+}
+
+// SetActiveTab switches to the tab at index tab, ex. DiffTab. It returns false and keeps the active tab if there's
+// no such tab.
+func (w *TabbedWindow) SetActiveTab(tab int) bool {
+	if tab < 0 || tab >= len(w.tabs) {
+		return false
+	}
+	w.activeTab = tab
+	return true
+}
+
+// NextTab switches to the tab after the active one, wrapping around to the first.
+func (w *TabbedWindow) NextTab() {
 	w.activeTab = (w.activeTab + 1) % len(w.tabs)
 }
 
@@ -109,20 +125,80 @@ func (w *TabbedWindow) UpdateDiff(instance *session.Instance) error {
 
 // Add these new methods for handling scroll events
 func (w *TabbedWindow) ScrollUp() {
-	if w.activeTab == 1 { // Diff tab
+	if w.activeTab == DiffTab {
 		w.diff.ScrollUp()
+	} else {
+		w.preview.ScrollUp()
 	}
 }
 
 func (w *TabbedWindow) ScrollDown() {
-	if w.activeTab == 1 { // Diff tab
+	if w.activeTab == DiffTab {
 		w.diff.ScrollDown()
+	} else {
+		w.preview.ScrollDown()
 	}
 }
 
-// IsInDiffTab returns true if the diff tab is currently active
-func (w *TabbedWindow) IsInDiffTab() bool {
-	return w.activeTab == 1
+// JumpToLine scrolls the active tab so line, counting from 1, is at the top.
+func (w *TabbedWindow) JumpToLine(line int) {
+	if w.activeTab == DiffTab {
+		w.diff.JumpToLine(line)
+	} else {
+		w.preview.JumpToLine(line)
+	}
+}
+
+// JumpToPercent scrolls the active tab percent of the way through its content.
+func (w *TabbedWindow) JumpToPercent(percent int) {
+	if w.activeTab == DiffTab {
+		w.diff.JumpToPercent(percent)
+	} else {
+		w.preview.JumpToPercent(percent)
+	}
+}
+
+// TogglePreviewFollowMode switches the preview between following the latest output and scrolling
+// through the history.
+func (w *TabbedWindow) TogglePreviewFollowMode() {
+	w.preview.ToggleFollowMode()
+}
+
+// SearchPreview highlights term in the preview's scrollback. An empty term clears the search.
+func (w *TabbedWindow) SearchPreview(term string) {
+	w.preview.Search(term)
+}
+
+// IsPreviewSearching returns true if there's an active search in the preview.
+func (w *TabbedWindow) IsPreviewSearching() bool {
+	return w.preview.IsSearching()
+}
+
+// NextPreviewMatch scrolls the preview to the next search match.
+func (w *TabbedWindow) NextPreviewMatch() {
+	w.preview.NextMatch()
+}
+
+// PrevPreviewMatch scrolls the preview to the previous search match.
+func (w *TabbedWindow) PrevPreviewMatch() {
+	w.preview.PrevMatch()
+}
+
+// PreviewSearchStatus describes the current match of the preview search.
+func (w *TabbedWindow) PreviewSearchStatus() string {
+	return w.preview.SearchStatus()
+}
+
+// ToggleChangedFiles switches the diff tab between the diff and the list of changed files, and switches to the
+// diff tab if it's not active.
+func (w *TabbedWindow) ToggleChangedFiles() {
+	if w.activeTab != DiffTab {
+		w.activeTab = DiffTab
+		if w.diff.IsShowingFiles() {
+			return
+		}
+	}
+	w.diff.ToggleFiles()
 }
 
 func (w *TabbedWindow) String() string {
@@ -166,10 +242,11 @@ func (w *TabbedWindow) String() string {
 
 	row := lipgloss.JoinHorizontal(lipgloss.Top, renderedTabs...)
 	var content string
-	if w.activeTab == 0 {
-		content = w.preview.String()
-	} else {
+	switch w.activeTab {
+	case DiffTab:
 		content = w.diff.String()
+	default:
+		content = w.preview.String()
 	}
 	window := windowStyle.Render(
 		lipgloss.Place(